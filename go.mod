@@ -3,14 +3,16 @@ module nrun
 go 1.19
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/google/shlex v0.0.0-20191202100458-e7afc7fbc510
+	github.com/prometheus-community/pro-bing v0.1.0
 	golang.org/x/crypto v0.6.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/go-ping/ping v1.1.0 // indirect
 	github.com/google/uuid v1.3.0 // indirect
-	github.com/prometheus-community/pro-bing v0.1.0 // indirect
 	golang.org/x/net v0.8.0 // indirect
 	golang.org/x/sync v0.1.0 // indirect
 	golang.org/x/sys v0.7.0 // indirect
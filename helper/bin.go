@@ -0,0 +1,128 @@
+package helper
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// IsWorkspaceRoot reports if the directory is the root of a npm/yarn/pnpm workspace or a repository.
+func IsWorkspaceRoot(dir string) bool {
+	if FileExists(dir+"/pnpm-workspace.yaml") || FileExists(dir+"/lerna.json") || FileExists(dir+"/.git") {
+		return true
+	}
+	file, err := os.ReadFile(dir + "/package.json")
+	if err != nil {
+		return false
+	}
+	packageJSON := PackageJSON{}
+	if json.Unmarshal(file, &packageJSON) != nil {
+		return false
+	}
+	return packageJSON.Workspaces != nil
+}
+
+// GetNodeBinPaths returns every node_modules/.bin from the path up to the workspace root,
+// nearest first, which is the same order npm uses when running scripts.
+func GetNodeBinPaths(path string) []string {
+	binPaths := []string{}
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return binPaths
+	}
+	for {
+		if IsDir(dir + "/node_modules/.bin") {
+			binPaths = append(binPaths, dir+"/node_modules/.bin")
+		}
+		if IsWorkspaceRoot(dir) {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return binPaths
+}
+
+var globalNpmBin *string
+
+// GetGlobalNpmBin returns the directory where globally installed npm packages put their binaries.
+// The answer from npm is cached on disk and only asked for again when the npm executable changes.
+func GetGlobalNpmBin() string {
+	if globalNpmBin != nil {
+		return *globalNpmBin
+	}
+	binPath := ""
+	globalNpmBin = &binPath
+
+	npmPath, err := exec.LookPath("npm")
+	if err != nil {
+		return binPath
+	}
+	cacheKey := npmPath
+	if stat, err := os.Stat(npmPath); err == nil {
+		cacheKey += ":" + strconv.FormatInt(stat.ModTime().Unix(), 10)
+	}
+
+	cacheFile := ""
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		cacheFile = cacheDir + "/nrun/npm-global-bin"
+		if cached, err := os.ReadFile(cacheFile); err == nil {
+			lines := strings.SplitN(string(cached), "\n", 2)
+			if len(lines) == 2 && lines[0] == cacheKey {
+				binPath = strings.TrimSpace(lines[1])
+				return binPath
+			}
+		}
+	}
+
+	output, err := exec.Command(npmPath, "prefix", "-g").Output()
+	if err != nil {
+		return binPath
+	}
+	prefix := strings.TrimSpace(string(output))
+	if len(prefix) == 0 {
+		return binPath
+	}
+	// On Windows the binaries are placed directly in the prefix
+	if runtime.GOOS == "windows" {
+		binPath = prefix
+	} else {
+		binPath = prefix + "/bin"
+	}
+	if cacheFile != "" {
+		if os.MkdirAll(filepath.Dir(cacheFile), 0755) == nil {
+			_ = os.WriteFile(cacheFile, []byte(cacheKey+"\n"+binPath), 0644)
+		}
+	}
+	return binPath
+}
+
+// GetScriptPath returns the PATH used when running package.json scripts in the path.
+func GetScriptPath(path string) string {
+	paths := GetNodeBinPaths(path)
+	if globalBin := GetGlobalNpmBin(); len(globalBin) > 0 && IsDir(globalBin) {
+		paths = append(paths, globalBin)
+	}
+	if currentPath := os.Getenv("PATH"); len(currentPath) > 0 {
+		paths = append(paths, currentPath)
+	}
+	return strings.Join(paths, string(os.PathListSeparator))
+}
+
+// SetEnvValue replaces (or adds) a KEY=value entry in an environment list.
+func SetEnvValue(env []string, key string, value string) []string {
+	newEnv := make([]string, 0, len(env)+1)
+	for _, envValue := range env {
+		if !strings.HasPrefix(envValue, key+"=") {
+			newEnv = append(newEnv, envValue)
+		}
+	}
+	return append(newEnv, key+"="+value)
+}
@@ -0,0 +1,391 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ConfigProblem struct {
+	File     string
+	Line     int
+	Column   int
+	Path     string
+	Message  string
+	IsError  bool
+	sortKey  int64
+	hasPlace bool
+}
+
+func (p ConfigProblem) String() string {
+	location := p.File
+	if p.hasPlace {
+		location += ":" + strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+	}
+	severity := "warning"
+	if p.IsError {
+		severity = "error"
+	}
+	if p.Path != "" {
+		return location + ": " + severity + ": " + p.Path + ": " + p.Message
+	}
+	return location + ": " + severity + ": " + p.Message
+}
+
+// The "package.json" section is untyped in Config but is validated with this shape
+type packageJSONOverrideSchema struct {
+	Scripts map[string]string `json:"scripts"`
+}
+
+var configSchemaOverrides = map[string]reflect.Type{
+	`["package.json"]`: reflect.TypeOf(map[string]packageJSONOverrideSchema{}),
+	"projects":         reflect.TypeOf(map[string]ProjectStruct{}),
+}
+
+// A project can be given as just its path instead of an object
+var projectSchemaType = reflect.TypeOf(ProjectStruct{})
+
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func appendJSONPath(path string, key string) string {
+	if identifierRegexp.MatchString(key) {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+	quoted, _ := json.Marshal(key)
+	return path + "[" + string(quoted) + "]"
+}
+
+// locateJSONValues returns the byte offset of every value in the document keyed by its path.
+func locateJSONValues(data []byte) map[string]int64 {
+	offsets := make(map[string]int64, 100)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var walk func(path string) error
+	walk = func(path string) error {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		// InputOffset points at the end of the previous token, skip whitespace and separators
+		for offset < int64(len(data)) && strings.ContainsRune(" \t\r\n,:", rune(data[offset])) {
+			offset++
+		}
+		offsets[path] = offset
+		delim, isDelim := token.(json.Delim)
+		if !isDelim {
+			return nil
+		}
+		if delim == '{' {
+			for decoder.More() {
+				keyOffset := decoder.InputOffset()
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				key, _ := keyToken.(string)
+				for keyOffset < int64(len(data)) && strings.ContainsRune(" \t\r\n,", rune(data[keyOffset])) {
+					keyOffset++
+				}
+				offsets["key:"+appendJSONPath(path, key)] = keyOffset
+				if err := walk(appendJSONPath(path, key)); err != nil {
+					return err
+				}
+			}
+		} else if delim == '[' {
+			for index := 0; decoder.More(); index++ {
+				if err := walk(path + "[" + strconv.Itoa(index) + "]"); err != nil {
+					return err
+				}
+			}
+		}
+		_, err = decoder.Token()
+		return err
+	}
+	_ = walk("")
+	return offsets
+}
+
+func offsetToLineColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	line := 1 + bytes.Count(data[:offset], []byte("\n"))
+	column := int(offset) + 1
+	if index := bytes.LastIndexByte(data[:offset], '\n'); index >= 0 {
+		column = int(offset) - index
+	}
+	return line, column
+}
+
+func jsonKindName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+func typeName(expected reflect.Type) string {
+	switch expected.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int64, reflect.Float64:
+		return "number"
+	case reflect.Slice:
+		return "array of " + typeName(expected.Elem())
+	case reflect.Map:
+		return "object of " + typeName(expected.Elem())
+	case reflect.Struct:
+		return "object"
+	}
+	return "any value"
+}
+
+type configChecker struct {
+	file     string
+	data     []byte
+	offsets  map[string]int64
+	problems []ConfigProblem
+}
+
+func (c *configChecker) report(path string, isError bool, message string) {
+	problem := ConfigProblem{File: c.file, Path: path, Message: message, IsError: isError}
+	offset, ok := c.offsets["key:"+path]
+	if !ok {
+		offset, ok = c.offsets[path]
+	}
+	if ok {
+		problem.Line, problem.Column = offsetToLineColumn(c.data, offset)
+		problem.sortKey = offset
+		problem.hasPlace = true
+	}
+	c.problems = append(c.problems, problem)
+}
+
+func (c *configChecker) validate(path string, value interface{}, expected reflect.Type) {
+	if override, ok := configSchemaOverrides[path]; ok {
+		expected = override
+	}
+	if value == nil {
+		return
+	}
+	if _, ok := value.(string); ok && expected == projectSchemaType {
+		return
+	}
+	switch expected.Kind() {
+	case reflect.Interface:
+		return
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			c.report(path, true, "expected string, found "+jsonKindName(value))
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			c.report(path, true, "expected boolean, found "+jsonKindName(value))
+		}
+	case reflect.Int, reflect.Int64, reflect.Float64:
+		if _, ok := value.(float64); !ok {
+			c.report(path, true, "expected number, found "+jsonKindName(value))
+		}
+	case reflect.Slice:
+		list, ok := value.([]interface{})
+		if !ok {
+			c.report(path, true, "expected "+typeName(expected)+", found "+jsonKindName(value))
+			return
+		}
+		for index, item := range list {
+			c.validate(path+"["+strconv.Itoa(index)+"]", item, expected.Elem())
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			c.report(path, true, "expected "+typeName(expected)+", found "+jsonKindName(value))
+			return
+		}
+		for _, key := range sortedKeys(object) {
+			c.validate(appendJSONPath(path, key), object[key], expected.Elem())
+		}
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			c.report(path, true, "expected object, found "+jsonKindName(value))
+			return
+		}
+		fields := make(map[string]reflect.Type, expected.NumField())
+		for i := 0; i < expected.NumField(); i++ {
+			name := strings.Split(expected.Field(i).Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			fields[strings.ToLower(name)] = expected.Field(i).Type
+		}
+		for _, key := range sortedKeys(object) {
+			fieldType, ok := fields[strings.ToLower(key)]
+			if !ok {
+				c.report(appendJSONPath(path, key), false, "unknown key \""+key+"\"")
+				continue
+			}
+			c.validate(appendJSONPath(path, key), object[key], fieldType)
+		}
+	}
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkProjectReferences reports "@project" keys in the path keyed sections that point to unknown projects.
+func (c *configChecker) checkProjectReferences(document map[string]interface{}, projects map[string]string) {
+	for _, section := range []string{"env", "path", "pipes", "package.json"} {
+		entries, ok := document[section].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range sortedKeys(entries) {
+			for _, part := range strings.Split(key, ",") {
+				part = strings.TrimSpace(part)
+				if len(part) > 1 && part[0] == '@' {
+					if _, ok := projects[part[1:]]; !ok {
+						c.report(appendJSONPath(appendJSONPath("", section), key), false, "the project \""+part[1:]+"\" is not defined")
+					}
+				}
+			}
+		}
+	}
+}
+
+// CheckConfigData validates a config document. Syntax errors stop the validation, everything
+// else is collected so all problems can be reported at once.
+func CheckConfigData(file string, data []byte, projects map[string]string) []ConfigProblem {
+	jsonData, offsets, problem := parseConfigData(file, data)
+	if problem != nil {
+		return []ConfigProblem{*problem}
+	}
+	checker := &configChecker{file: file, data: data, offsets: offsets}
+	var document interface{}
+	_ = json.Unmarshal(jsonData, &document)
+	checker.validate("", document, reflect.TypeOf(Config{}))
+	if object, ok := document.(map[string]interface{}); ok {
+		if projects == nil {
+			projects = make(map[string]string)
+			if projectList, ok := object["projects"].(map[string]interface{}); ok {
+				for name, project := range projectList {
+					projects[name], _ = projectPath(project)
+				}
+			}
+		}
+		checker.checkProjectReferences(object, projects)
+		if projectList, ok := object["projects"].(map[string]interface{}); ok {
+			for _, name := range sortedKeys(projectList) {
+				if project, ok := projectList[name].(map[string]interface{}); ok {
+					if _, ok := project["path"].(string); !ok {
+						checker.report(appendJSONPath("projects", name), true, "the project has no path")
+					}
+				}
+			}
+		}
+	}
+	sort.SliceStable(checker.problems, func(i, j int) bool {
+		return checker.problems[i].sortKey < checker.problems[j].sortKey
+	})
+	return checker.problems
+}
+
+func CheckConfigFile(file string, projects map[string]string) ([]ConfigProblem, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return CheckConfigData(file, data, projects), nil
+}
+
+// describeConfigError turns an error from reading a config into a message with the location in the file.
+func describeConfigError(file string, data []byte, err error) error {
+	for _, problem := range CheckConfigData(file, data, nil) {
+		if problem.IsError {
+			return errors.New(problem.String() + " (run nrun --config-check for details)")
+		}
+	}
+	return fmt.Errorf("%s: %s", file, err)
+}
+
+func ConfigCheck(files []string) int {
+	exitCode := 0
+	// Projects can only be defined in the global config files, so every file is checked against those
+	var projects map[string]string
+	if config, err := GetEffectiveConfig(""); err == nil {
+		projects = config.Projects
+	}
+	files, includeErrors := ExpandConfigIncludes(files, projects)
+	for _, err := range includeErrors {
+		fmt.Println(err)
+		exitCode = 1
+	}
+	checked := 0
+	for _, file := range files {
+		if !FileExists(file) {
+			continue
+		}
+		checked++
+		problems, err := CheckConfigFile(file, projects)
+		if err != nil {
+			fmt.Println(file+":", err)
+			exitCode = 1
+			continue
+		}
+		document, _ := readConfigDocument(file)
+		if version := readConfigVersion(file); version > CurrentConfigVersion {
+			problems = append(problems, ConfigProblem{File: file, Path: "version", Message: fmt.Sprintf("the file uses config version %d but this nrun only knows version %d, please upgrade nrun", version, CurrentConfigVersion)})
+		} else if configNeedsMigration(document, projects) {
+			problems = append(problems, ConfigProblem{File: file, Message: fmt.Sprintf("the file uses config version %d, run nrun --config-migrate to upgrade it to version %d", version, CurrentConfigVersion)})
+		}
+		errorCount, warningCount := 0, 0
+		for _, problem := range problems {
+			fmt.Println(problem)
+			if problem.IsError {
+				errorCount++
+			} else {
+				warningCount++
+			}
+		}
+		if errorCount > 0 {
+			exitCode = 1
+		}
+		if len(problems) == 0 {
+			fmt.Println(file + ": OK")
+		} else {
+			fmt.Printf("%s: %d error(s), %d warning(s)\n", file, errorCount, warningCount)
+		}
+	}
+	if checked == 0 {
+		fmt.Println("No config files found")
+	}
+	return exitCode
+}
@@ -0,0 +1,404 @@
+package helper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A configPathSegment is either a key in an object or an index in a list
+type configPathSegment struct {
+	Key     string
+	Index   int
+	IsIndex bool
+}
+
+// ParseConfigPath parses a path in the dotted form used by --config-check, e.g. env["/Users/me/app"].start
+// or scripts.test[0], or a JSON pointer like /env/~1Users~1me~1app/start.
+func ParseConfigPath(path string) ([]configPathSegment, error) {
+	segments := []configPathSegment{}
+	if len(path) == 0 {
+		return segments, nil
+	}
+	if path[0] == '/' {
+		for _, part := range strings.Split(path[1:], "/") {
+			part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+			if index, err := strconv.Atoi(part); err == nil && index >= 0 {
+				segments = append(segments, configPathSegment{Key: part, Index: index, IsIndex: true})
+			} else {
+				segments = append(segments, configPathSegment{Key: part})
+			}
+		}
+		return segments, nil
+	}
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if i+1 < len(path) && path[i+1] == '"' {
+				// Find the closing quote, the key may contain ] and escaped quotes
+				end = -1
+				for j := i + 2; j < len(path); j++ {
+					if path[j] == '\\' {
+						j++
+					} else if path[j] == '"' {
+						if j+1 < len(path) && path[j+1] == ']' {
+							end = j + 1 - i
+						}
+						break
+					}
+				}
+			}
+			if end < 0 {
+				return nil, errors.New("missing ] in path \"" + path + "\"")
+			}
+			inner := path[i+1 : i+end]
+			if len(inner) > 0 && inner[0] == '"' {
+				var key string
+				if err := json.Unmarshal([]byte(inner), &key); err != nil {
+					return nil, errors.New("invalid key " + inner + " in path \"" + path + "\"")
+				}
+				segments = append(segments, configPathSegment{Key: key})
+			} else if index, err := strconv.Atoi(inner); err == nil && index >= 0 {
+				segments = append(segments, configPathSegment{Key: inner, Index: index, IsIndex: true})
+			} else {
+				segments = append(segments, configPathSegment{Key: inner})
+			}
+			i += end + 1
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			segments = append(segments, configPathSegment{Key: path[i : i+end]})
+			i += end
+		}
+	}
+	return segments, nil
+}
+
+func formatConfigPath(segments []configPathSegment) string {
+	path := ""
+	for _, segment := range segments {
+		if segment.IsIndex {
+			path += "[" + strconv.Itoa(segment.Index) + "]"
+		} else {
+			path = appendJSONPath(path, segment.Key)
+		}
+	}
+	return path
+}
+
+// configSchemaType returns the Go type the config expects at the path, or nil if anything goes. The second
+// value is false if the config doesn't have the path.
+func configSchemaType(segments []configPathSegment) (reflect.Type, bool) {
+	expected := reflect.TypeOf(Config{})
+	path := ""
+	for _, segment := range segments {
+		if segment.IsIndex {
+			path += "[" + strconv.Itoa(segment.Index) + "]"
+		} else {
+			path = appendJSONPath(path, segment.Key)
+		}
+		switch expected.Kind() {
+		case reflect.Struct:
+			var found reflect.Type
+			for i := 0; i < expected.NumField(); i++ {
+				if strings.EqualFold(strings.Split(expected.Field(i).Tag.Get("json"), ",")[0], segment.Key) {
+					found = expected.Field(i).Type
+				}
+			}
+			if found == nil {
+				return nil, false
+			}
+			expected = found
+		case reflect.Map, reflect.Slice:
+			expected = expected.Elem()
+		case reflect.Interface:
+			return nil, true
+		default:
+			return nil, false
+		}
+		if override, ok := configSchemaOverrides[path]; ok {
+			expected = override
+		}
+	}
+	if expected.Kind() == reflect.Interface || expected == projectSchemaType {
+		return nil, true
+	}
+	return expected, true
+}
+
+// ParseConfigValue converts the value given on the command line to the type expected at the path.
+// The value type can be forced with "string", "number", "bool" or "json".
+func ParseConfigValue(value string, valueType string, expected reflect.Type) (interface{}, error) {
+	if valueType == "" {
+		valueType = "json"
+		if expected != nil {
+			switch expected.Kind() {
+			case reflect.String:
+				valueType = "string"
+			case reflect.Bool:
+				valueType = "bool"
+			case reflect.Int, reflect.Int64, reflect.Float64:
+				valueType = "number"
+			}
+		}
+	}
+	switch valueType {
+	case "string":
+		return value, nil
+	case "bool":
+		return strconv.ParseBool(value)
+	case "number":
+		return strconv.ParseFloat(value, 64)
+	case "json":
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+			// A list of strings can also be given as a comma separated list
+			if expected != nil && expected.Kind() == reflect.Slice && expected.Elem().Kind() == reflect.String {
+				list := []interface{}{}
+				for _, item := range strings.Split(value, ",") {
+					list = append(list, strings.TrimSpace(item))
+				}
+				return list, nil
+			}
+			if expected == nil {
+				return value, nil
+			}
+			return nil, errors.New("the value isn't valid JSON: " + err.Error())
+		}
+		return parsed, nil
+	}
+	return nil, errors.New("unknown value type \"" + valueType + "\"")
+}
+
+func getConfigValue(document interface{}, segments []configPathSegment) (interface{}, bool) {
+	current := document
+	for _, segment := range segments {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[segment.Key]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			if !segment.IsIndex || segment.Index >= len(node) {
+				return nil, false
+			}
+			current = node[segment.Index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// setConfigValue sets (or with a nil value removes) the value at the path, creating objects on the way.
+func setConfigValue(document interface{}, segments []configPathSegment, value interface{}, remove bool) (interface{}, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+	segment := segments[0]
+	switch node := document.(type) {
+	case nil:
+		if remove {
+			return nil, errors.New("\"" + segment.Key + "\" doesn't exist")
+		}
+		if segment.IsIndex {
+			return setConfigValue([]interface{}{}, segments, value, remove)
+		}
+		return setConfigValue(map[string]interface{}{}, segments, value, remove)
+	case map[string]interface{}:
+		if remove && len(segments) == 1 {
+			if _, ok := node[segment.Key]; !ok {
+				return nil, errors.New("\"" + segment.Key + "\" doesn't exist")
+			}
+			delete(node, segment.Key)
+			return node, nil
+		}
+		child, err := setConfigValue(node[segment.Key], segments[1:], value, remove)
+		if err != nil {
+			return nil, err
+		}
+		node[segment.Key] = child
+		return node, nil
+	case []interface{}:
+		if !segment.IsIndex {
+			return nil, errors.New("\"" + segment.Key + "\" isn't an index in a list")
+		}
+		if remove && len(segments) == 1 {
+			if segment.Index >= len(node) {
+				return nil, errors.New("index " + segment.Key + " doesn't exist")
+			}
+			return append(node[:segment.Index], node[segment.Index+1:]...), nil
+		}
+		// Setting the index after the last item appends to the list
+		if segment.Index == len(node) && !remove {
+			node = append(node, nil)
+		}
+		if segment.Index >= len(node) {
+			return nil, errors.New("index " + segment.Key + " is out of range")
+		}
+		child, err := setConfigValue(node[segment.Index], segments[1:], value, remove)
+		if err != nil {
+			return nil, err
+		}
+		node[segment.Index] = child
+		return node, nil
+	}
+	return nil, errors.New("can't descend into a " + jsonKindName(document) + " at \"" + segment.Key + "\"")
+}
+
+func configToDocument(config *Config) map[string]interface{} {
+	document := make(map[string]interface{})
+	if config == nil {
+		return document
+	}
+	data, _ := json.Marshal(config)
+	_ = json.Unmarshal(data, &document)
+	return document
+}
+
+func printConfigValues(path string, value interface{}) {
+	switch node := value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(node) {
+			printConfigValues(appendJSONPath(path, key), node[key])
+		}
+	case []interface{}:
+		for index, item := range node {
+			printConfigValues(path+"["+strconv.Itoa(index)+"]", item)
+		}
+	default:
+		data, _ := json.Marshal(node)
+		fmt.Println(path, "=", string(data))
+	}
+}
+
+// GetLocalConfigTarget returns the closest local config file, or a new .nrun.json in the current directory.
+func GetLocalConfigTarget() string {
+	cwd, _ := os.Getwd()
+	files := GetLocalConfigFiles(cwd, cwd)
+	if len(files) > 0 {
+		return files[len(files)-1]
+	}
+	return FindConfigFile(cwd, ".nrun")
+}
+
+func ConfigCommand(args []string, flagList *FlagList) error {
+	if len(args) == 0 {
+		return errors.New("usage: nrun --config get|set|unset|list [path] [value]")
+	}
+	command := args[0]
+	args = args[1:]
+
+	file := ""
+	if flagList.ConfigLocal != nil && *flagList.ConfigLocal {
+		file = GetLocalConfigTarget()
+	} else if flagList.ConfigGlobal != nil && *flagList.ConfigGlobal {
+		file = GetGlobalConfigFile()
+	} else if command == "set" || command == "unset" {
+		file = GetGlobalConfigFile()
+	}
+
+	var document map[string]interface{}
+	if file == "" {
+		cwd, _ := os.Getwd()
+		effective, err := GetEffectiveConfigDocument(cwd)
+		if err != nil {
+			return err
+		}
+		document = effective
+	} else {
+		config, err := ReadConfig(file)
+		if err != nil && err.Error() != "config file not found" {
+			return err
+		}
+		document = configToDocument(config)
+	}
+
+	path := ""
+	if len(args) > 0 {
+		path = args[0]
+	}
+	segments, err := ParseConfigPath(path)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "get", "list":
+		value, ok := getConfigValue(document, segments)
+		if !ok {
+			return errors.New("\"" + path + "\" isn't set")
+		}
+		if command == "list" {
+			printConfigValues(formatConfigPath(segments), value)
+			return nil
+		}
+		if text, ok := value.(string); ok {
+			fmt.Println(text)
+			return nil
+		}
+		data, _ := json.MarshalIndent(value, "", "  ")
+		fmt.Println(string(data))
+		return nil
+	case "set", "unset":
+		if len(segments) == 0 {
+			return errors.New("a path is required")
+		}
+		if file != GetGlobalConfigFile() && Contains(globalOnlyConfigSections, strings.ToLower(segments[0].Key)) {
+			return errors.New("\"" + segments[0].Key + "\" can only be defined in the global config")
+		}
+		expected, known := configSchemaType(segments)
+		if !known {
+			return errors.New("\"" + formatConfigPath(segments) + "\" isn't a known config setting")
+		}
+		var value interface{}
+		if command == "set" {
+			if len(args) < 2 {
+				return errors.New("usage: nrun --config set <path> <value>")
+			}
+			valueType := ""
+			if flagList.ConfigType != nil {
+				valueType = *flagList.ConfigType
+			}
+			value, err = ParseConfigValue(strings.Join(args[1:], " "), valueType, expected)
+			if err != nil {
+				return err
+			}
+		}
+		err = UpdateConfig(file, func(config *Config) error {
+			updated, err := setConfigValue(configToDocument(config), segments, value, command == "unset")
+			if err != nil {
+				return err
+			}
+			data, _ := json.Marshal(updated)
+			for _, problem := range CheckConfigData("", data, map[string]string{}) {
+				if problem.IsError {
+					return errors.New(problem.Path + ": " + problem.Message)
+				}
+			}
+			*config = Config{}
+			return json.Unmarshal(data, config)
+		})
+		if err != nil {
+			return err
+		}
+		if command == "set" {
+			fmt.Println("Set", formatConfigPath(segments), "in", file)
+		} else {
+			fmt.Println("Removed", formatConfigPath(segments), "from", file)
+		}
+		return nil
+	}
+	return errors.New("unknown config command \"" + command + "\", use get, set, unset or list")
+}
@@ -0,0 +1,236 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// Sections that can only be defined in the global config files
+var globalOnlyConfigSections = []string{"projects", "scripts"}
+
+// GetGlobalConfigFile returns ~/.nrun.json, or ~/.nrun.jsonc, ~/.nrun.yaml etc. if one of those is used instead.
+func GetGlobalConfigFile() string {
+	usr, _ := user.Current()
+	return FindConfigFile(usr.HomeDir, ".nrun")
+}
+
+func GetXDGConfigFile() string {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if len(configHome) == 0 {
+		usr, _ := user.Current()
+		configHome = usr.HomeDir + "/.config"
+	}
+	return FindConfigFile(configHome+"/nrun", "config")
+}
+
+// GetGlobalConfigFiles returns the global config files, lowest precedence first.
+func GetGlobalConfigFiles() []string {
+	return []string{GetXDGConfigFile(), GetGlobalConfigFile()}
+}
+
+func findRepositoryRoot(path string) string {
+	for dir := path; ; {
+		if FileExists(dir + "/.git") {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// GetLocalConfigFiles returns the local .nrun.json (or .nrun.jsonc, .nrun.yaml, .nrun.toml) files from the
+// repository root (or the project root if it isn't in a repository) down to the current directory, lowest
+// precedence first.
+func GetLocalConfigFiles(cwd string, projectPath string) []string {
+	files := []string{}
+	if len(projectPath) == 0 {
+		return files
+	}
+	projectPath, _ = filepath.Abs(projectPath)
+	cwd, _ = filepath.Abs(cwd)
+	start := cwd
+	if start != projectPath && !strings.HasPrefix(start, projectPath+string(os.PathSeparator)) {
+		start = projectPath
+	}
+	stop := projectPath
+	if repositoryRoot := findRepositoryRoot(projectPath); len(repositoryRoot) > 0 {
+		stop = repositoryRoot
+	}
+	usr, _ := user.Current()
+	for dir := start; ; {
+		// The .nrun.json in the home directory is the global config
+		if dir == usr.HomeDir {
+			break
+		}
+		if file := FindConfigFile(dir, ".nrun"); FileExists(file) {
+			files = append([]string{file}, files...)
+		}
+		parent := filepath.Dir(dir)
+		if dir == stop || parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return files
+}
+
+// GetConfigFiles returns every config file that applies to the path, lowest precedence first.
+// Only the global files are returned if the path is empty.
+func GetConfigFiles(path string) []string {
+	files := GetGlobalConfigFiles()
+	if len(path) > 0 {
+		cwd, _ := os.Getwd()
+		files = append(files, GetLocalConfigFiles(cwd, path)...)
+	}
+	return files
+}
+
+// LoadConfigDocument reads a config file as a generic document. The file is validated with ReadConfig first.
+func LoadConfigDocument(file string) (map[string]interface{}, error) {
+	if _, err := ReadConfig(file); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	jsonData, _, problem := parseConfigData(file, data)
+	if problem != nil {
+		return nil, errors.New(problem.String())
+	}
+	document := make(map[string]interface{})
+	if err := json.Unmarshal(jsonData, &document); err != nil {
+		return nil, describeConfigError(file, data, err)
+	}
+	warnNewerConfig(file, document)
+	return document, nil
+}
+
+// MergeConfigDocuments merges src into dst. Objects are merged key by key, everything else
+// (strings, numbers and lists) in src replaces the value in dst.
+func MergeConfigDocuments(dst map[string]interface{}, src map[string]interface{}) {
+	mergeConfigDocuments(dst, src, "", nil, nil, "")
+}
+
+// mergeConfigDocuments is MergeConfigDocuments that also records where every value came from. The source
+// of a value is taken from srcSources, or is file if srcSources doesn't know about it.
+func mergeConfigDocuments(dst map[string]interface{}, src map[string]interface{}, path string, sources map[string]string, srcSources map[string]string, file string) {
+	for key, value := range src {
+		childPath := appendJSONPath(path, key)
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && !dstIsMap {
+			dstMap = make(map[string]interface{}, len(srcMap))
+			dst[key] = dstMap
+		}
+		if srcIsMap {
+			mergeConfigDocuments(dstMap, srcMap, childPath, sources, srcSources, file)
+			if len(srcMap) > 0 || sources == nil {
+				continue
+			}
+		} else if value != nil {
+			dst[key] = value
+		} else {
+			continue
+		}
+		if sources != nil {
+			if source, ok := srcSources[childPath]; ok {
+				sources[childPath] = source
+			} else {
+				sources[childPath] = file
+			}
+		}
+	}
+}
+
+func copyConfigDocument(document map[string]interface{}) map[string]interface{} {
+	documentCopy := make(map[string]interface{}, len(document))
+	MergeConfigDocuments(documentCopy, document)
+	return documentCopy
+}
+
+var effectiveConfigCache = make(map[string]map[string]interface{}, 10)
+var effectiveConfigSources = make(map[string]map[string]string, 10)
+var effectiveConfigFiles = make(map[string][]string, 10)
+
+// GetEffectiveConfigDocument merges all config files (and the files they include) that apply to the path.
+func GetEffectiveConfigDocument(path string) (map[string]interface{}, error) {
+	if document, ok := effectiveConfigCache[path]; ok {
+		return document, nil
+	}
+	effective := make(map[string]interface{})
+	sources := make(map[string]string)
+	files := []string{}
+	globalFiles := len(GetGlobalConfigFiles())
+	for index, file := range GetConfigFiles(path) {
+		if !FileExists(file) {
+			continue
+		}
+		projects := getConfigProjects(effective, nil)
+		documentSources := make(map[string]string)
+		document, err := LoadConfigWithIncludes(file, projects, nil, documentSources, &files)
+		if err != nil {
+			return nil, err
+		}
+		if index >= globalFiles {
+			for _, section := range globalOnlyConfigSections {
+				delete(document, section)
+			}
+		}
+		mergeConfigDocuments(effective, document, "", sources, documentSources, file)
+	}
+	if len(activeProfile) > 0 {
+		applyConfigProfile(effective, sources, activeProfile)
+	}
+	effectiveConfigCache[path] = effective
+	effectiveConfigSources[path] = sources
+	effectiveConfigFiles[path] = files
+	return effective, nil
+}
+
+func GetEffectiveConfig(path string) (*Config, error) {
+	document, err := GetEffectiveConfigDocument(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	config := Config{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// ShowEffectiveConfig prints the merged config with the file every value came from as a comment.
+func ShowEffectiveConfig(path string, flagList *FlagList) error {
+	document, err := GetEffectiveConfigDocument(path)
+	if err != nil {
+		return err
+	}
+	if flagList.BeVerbose != nil && *flagList.BeVerbose {
+		fmt.Println("Config files (lowest precedence first):")
+		for _, file := range effectiveConfigFiles[path] {
+			fmt.Println("  " + file)
+		}
+	}
+	layout := &jsoncLayout{order: map[string]int64{}, leading: map[string][]string{}, trailing: map[string]string{}}
+	for valuePath, file := range effectiveConfigSources[path] {
+		layout.trailing[valuePath] = "// " + file
+	}
+	var buffer bytes.Buffer
+	layout.write(&buffer, document, "", "")
+	fmt.Println(buffer.String())
+	return nil
+}
@@ -0,0 +1,491 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported config file extensions. If a directory has more than one config file the first one in this list is used.
+var configExtensions = []string{".json", ".jsonc", ".yaml", ".yml", ".toml"}
+
+func GetConfigFormat(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".jsonc":
+		return "jsonc"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	}
+	return "json"
+}
+
+// FindConfigFile returns the config file in the directory with the given base name (e.g. ".nrun"),
+// in any of the supported formats. The JSON file name is returned if none of them exists.
+func FindConfigFile(dir string, base string) string {
+	for _, extension := range configExtensions {
+		if FileExists(dir + "/" + base + extension) {
+			return dir + "/" + base + extension
+		}
+	}
+	return dir + "/" + base + ".json"
+}
+
+type jsonComment struct {
+	start int
+	end   int
+	text  string
+}
+
+// stripJSONC blanks out comments and trailing commas. Everything is replaced with spaces (newlines
+// are kept) so offsets in the result are the same as in the original.
+func stripJSONC(data []byte) ([]byte, []jsonComment) {
+	stripped := make([]byte, len(data))
+	copy(stripped, data)
+	comments := []jsonComment{}
+	inString := false
+	for i := 0; i < len(stripped); i++ {
+		c := stripped[i]
+		if inString {
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		if c == '"' {
+			inString = true
+		} else if c == '/' && i+1 < len(stripped) && (stripped[i+1] == '/' || stripped[i+1] == '*') {
+			end := len(stripped)
+			if stripped[i+1] == '/' {
+				if index := bytes.IndexByte(stripped[i:], '\n'); index >= 0 {
+					end = i + index
+				}
+			} else if index := bytes.Index(stripped[i+2:], []byte("*/")); index >= 0 {
+				end = i + 2 + index + 2
+			}
+			comments = append(comments, jsonComment{start: i, end: end, text: strings.TrimRight(string(data[i:end]), " \t\r")})
+			for j := i; j < end; j++ {
+				if stripped[j] != '\n' {
+					stripped[j] = ' '
+				}
+			}
+			i = end - 1
+		}
+	}
+	// Remove trailing commas
+	inString = false
+	for i := 0; i < len(stripped); i++ {
+		c := stripped[i]
+		if inString {
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		if c == '"' {
+			inString = true
+		} else if c == ',' {
+			j := i + 1
+			for j < len(stripped) && strings.ContainsRune(" \t\r\n", rune(stripped[j])) {
+				j++
+			}
+			if j < len(stripped) && (stripped[j] == '}' || stripped[j] == ']') {
+				stripped[i] = ' '
+			}
+		}
+	}
+	return stripped, comments
+}
+
+func lineColumnToOffset(data []byte, line int, column int) int64 {
+	offset := 0
+	for currentLine := 1; currentLine < line; currentLine++ {
+		index := bytes.IndexByte(data[offset:], '\n')
+		if index < 0 {
+			return int64(len(data))
+		}
+		offset += index + 1
+	}
+	return int64(offset + column - 1)
+}
+
+// locateYAMLValues returns the offsets of keys and values in a YAML document in the same form as locateJSONValues.
+func locateYAMLValues(data []byte) map[string]int64 {
+	offsets := make(map[string]int64, 100)
+	var root yaml.Node
+	if yaml.Unmarshal(data, &root) != nil {
+		return offsets
+	}
+	var walk func(node *yaml.Node, path string)
+	walk = func(node *yaml.Node, path string) {
+		if node.Kind == yaml.DocumentNode {
+			for _, child := range node.Content {
+				walk(child, path)
+			}
+			return
+		}
+		offsets[path] = lineColumnToOffset(data, node.Line, node.Column)
+		if node.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				childPath := appendJSONPath(path, node.Content[i].Value)
+				offsets["key:"+childPath] = lineColumnToOffset(data, node.Content[i].Line, node.Content[i].Column)
+				walk(node.Content[i+1], childPath)
+			}
+		} else if node.Kind == yaml.SequenceNode {
+			for index, child := range node.Content {
+				walk(child, path+"["+strconv.Itoa(index)+"]")
+			}
+		}
+	}
+	walk(&root, "")
+	return offsets
+}
+
+// parseConfigData converts a config file in any of the supported formats to JSON. The offsets of
+// the values in the original data are returned as well, if they are known for the format.
+func parseConfigData(file string, data []byte) ([]byte, map[string]int64, *ConfigProblem) {
+	switch GetConfigFormat(file) {
+	case "jsonc":
+		stripped, _ := stripJSONC(data)
+		if problem := jsonSyntaxProblem(file, data, stripped); problem != nil {
+			return nil, nil, problem
+		}
+		return stripped, locateJSONValues(stripped), nil
+	case "yaml":
+		var document interface{}
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return nil, nil, &ConfigProblem{File: file, Message: err.Error(), IsError: true}
+		}
+		jsonData, err := marshalConfigDocument(document)
+		if err != nil {
+			return nil, nil, &ConfigProblem{File: file, Message: err.Error(), IsError: true}
+		}
+		return jsonData, locateYAMLValues(data), nil
+	case "toml":
+		document := make(map[string]interface{})
+		if err := toml.Unmarshal(data, &document); err != nil {
+			problem := &ConfigProblem{File: file, Message: err.Error(), IsError: true}
+			var parseError toml.ParseError
+			if errors.As(err, &parseError) {
+				problem.Message = parseError.Message
+				if len(problem.Message) == 0 {
+					problem.Message = tomlErrorPrefixRegexp.ReplaceAllString(parseError.Error(), "")
+				}
+				problem.Line, problem.Column = offsetToLineColumn(data, int64(parseError.Position.Start))
+				problem.hasPlace = true
+			}
+			return nil, nil, problem
+		}
+		jsonData, err := marshalConfigDocument(document)
+		if err != nil {
+			return nil, nil, &ConfigProblem{File: file, Message: err.Error(), IsError: true}
+		}
+		return jsonData, map[string]int64{}, nil
+	}
+	if problem := jsonSyntaxProblem(file, data, data); problem != nil {
+		return nil, nil, problem
+	}
+	return data, locateJSONValues(data), nil
+}
+
+func jsonSyntaxProblem(file string, original []byte, data []byte) *ConfigProblem {
+	var document interface{}
+	err := json.Unmarshal(data, &document)
+	if err == nil {
+		return nil
+	}
+	problem := &ConfigProblem{File: file, Message: err.Error(), IsError: true}
+	var syntaxError *json.SyntaxError
+	if errors.As(err, &syntaxError) {
+		problem.Line, problem.Column = offsetToLineColumn(original, syntaxError.Offset)
+		problem.hasPlace = true
+	}
+	return problem
+}
+
+// marshalConfigDocument marshals a decoded YAML or TOML document as JSON. Keys are always strings in a config.
+func marshalConfigDocument(document interface{}) ([]byte, error) {
+	var convert func(value interface{}) interface{}
+	convert = func(value interface{}) interface{} {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			for key, child := range node {
+				node[key] = convert(child)
+			}
+		case map[interface{}]interface{}:
+			converted := make(map[string]interface{}, len(node))
+			for key, child := range node {
+				converted[toString(key)] = convert(child)
+			}
+			return converted
+		case []interface{}:
+			for index, child := range node {
+				node[index] = convert(child)
+			}
+		case []map[string]interface{}:
+			list := make([]interface{}, len(node))
+			for index, child := range node {
+				list[index] = convert(child)
+			}
+			return list
+		}
+		return value
+	}
+	return json.Marshal(convert(document))
+}
+
+func toString(value interface{}) string {
+	if text, ok := value.(string); ok {
+		return text
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// EncodeConfig returns the config in the format given by the file extension. If the file already
+// exists the key order and comments are kept for JSONC and YAML.
+func EncodeConfig(file string, config *Config) ([]byte, error) {
+	format := GetConfigFormat(file)
+	if format == "json" {
+		var buffer bytes.Buffer
+		encoder := json.NewEncoder(&buffer)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(config)
+		return buffer.Bytes(), err
+	}
+	document := configToDocument(config)
+	original, _ := os.ReadFile(file)
+	switch format {
+	case "jsonc":
+		return encodeJSONC(document, original), nil
+	case "yaml":
+		return encodeYAML(document, original)
+	}
+	var buffer bytes.Buffer
+	err := toml.NewEncoder(&buffer).Encode(document)
+	return buffer.Bytes(), err
+}
+
+type jsoncLayout struct {
+	order    map[string]int64
+	leading  map[string][]string
+	trailing map[string]string
+	header   []string
+	footer   []string
+}
+
+var tomlErrorPrefixRegexp = regexp.MustCompile(`^toml: line [0-9]+( \(last key .*\))?: `)
+
+var arrayItemRegexp = regexp.MustCompile(`\[[0-9]+\]$`)
+
+func newJSONCLayout(original []byte) *jsoncLayout {
+	layout := &jsoncLayout{order: map[string]int64{}, leading: map[string][]string{}, trailing: map[string]string{}}
+	if len(original) == 0 {
+		return layout
+	}
+	stripped, comments := stripJSONC(original)
+	offsets := locateJSONValues(stripped)
+	type anchor struct {
+		path   string
+		offset int64
+	}
+	anchors := []anchor{}
+	for key, offset := range offsets {
+		if strings.HasPrefix(key, "key:") {
+			layout.order[key[4:]] = offset
+			anchors = append(anchors, anchor{key[4:], offset})
+		} else if arrayItemRegexp.MatchString(key) {
+			anchors = append(anchors, anchor{key, offset})
+		}
+	}
+	sort.Slice(anchors, func(i, j int) bool { return anchors[i].offset < anchors[j].offset })
+	documentStart := bytes.IndexByte(stripped, '{')
+	for _, comment := range comments {
+		if comment.start < documentStart {
+			layout.header = append(layout.header, comment.text)
+			continue
+		}
+		lineStart := bytes.LastIndexByte(original[:comment.start], '\n') + 1
+		sameLine := strings.TrimSpace(string(stripped[lineStart:comment.start])) != ""
+		if sameLine {
+			// A comment after a value belongs to the last anchor before it
+			for i := len(anchors) - 1; i >= 0; i-- {
+				if anchors[i].offset < int64(comment.start) {
+					layout.trailing[anchors[i].path] = comment.text
+					break
+				}
+			}
+			continue
+		}
+		attached := false
+		for _, anchor := range anchors {
+			if anchor.offset > int64(comment.end) {
+				layout.leading[anchor.path] = append(layout.leading[anchor.path], comment.text)
+				attached = true
+				break
+			}
+		}
+		if !attached {
+			layout.footer = append(layout.footer, comment.text)
+		}
+	}
+	return layout
+}
+
+func encodeJSONC(document map[string]interface{}, original []byte) []byte {
+	layout := newJSONCLayout(original)
+	var buffer bytes.Buffer
+	for _, comment := range layout.header {
+		buffer.WriteString(comment + "\n")
+	}
+	layout.write(&buffer, document, "", "")
+	buffer.WriteString("\n")
+	for _, comment := range layout.footer {
+		buffer.WriteString(comment + "\n")
+	}
+	return buffer.Bytes()
+}
+
+func (l *jsoncLayout) write(buffer *bytes.Buffer, value interface{}, path string, indent string) {
+	switch node := value.(type) {
+	case map[string]interface{}:
+		if len(node) == 0 {
+			buffer.WriteString("{}")
+			return
+		}
+		keys := sortedKeys(node)
+		sort.SliceStable(keys, func(i, j int) bool {
+			offsetI, okI := l.order[appendJSONPath(path, keys[i])]
+			offsetJ, okJ := l.order[appendJSONPath(path, keys[j])]
+			if okI && okJ {
+				return offsetI < offsetJ
+			}
+			return okI && !okJ
+		})
+		buffer.WriteString("{\n")
+		for index, key := range keys {
+			childPath := appendJSONPath(path, key)
+			l.writeLeading(buffer, childPath, indent+"  ")
+			quoted, _ := marshalJSONValue(key)
+			buffer.WriteString(indent + "  " + string(quoted) + ": ")
+			l.write(buffer, node[key], childPath, indent+"  ")
+			l.writeEnd(buffer, childPath, index == len(keys)-1)
+		}
+		buffer.WriteString(indent + "}")
+	case []interface{}:
+		if len(node) == 0 {
+			buffer.WriteString("[]")
+			return
+		}
+		buffer.WriteString("[\n")
+		for index, item := range node {
+			childPath := path + "[" + strconv.Itoa(index) + "]"
+			l.writeLeading(buffer, childPath, indent+"  ")
+			buffer.WriteString(indent + "  ")
+			l.write(buffer, item, childPath, indent+"  ")
+			l.writeEnd(buffer, childPath, index == len(node)-1)
+		}
+		buffer.WriteString(indent + "]")
+	default:
+		data, _ := marshalJSONValue(node)
+		buffer.Write(data)
+	}
+}
+
+func (l *jsoncLayout) writeLeading(buffer *bytes.Buffer, path string, indent string) {
+	for _, comment := range l.leading[path] {
+		buffer.WriteString(indent + comment + "\n")
+	}
+}
+
+func (l *jsoncLayout) writeEnd(buffer *bytes.Buffer, path string, last bool) {
+	if !last {
+		buffer.WriteString(",")
+	}
+	if comment, ok := l.trailing[path]; ok {
+		buffer.WriteString(" " + comment)
+	}
+	buffer.WriteString("\n")
+}
+
+func marshalJSONValue(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(value)
+	return bytes.TrimRight(buffer.Bytes(), "\n"), err
+}
+
+func encodeYAML(document map[string]interface{}, original []byte) ([]byte, error) {
+	var newNode yaml.Node
+	if err := newNode.Encode(document); err != nil {
+		return nil, err
+	}
+	var oldDocument yaml.Node
+	if len(original) > 0 && yaml.Unmarshal(original, &oldDocument) == nil && len(oldDocument.Content) > 0 {
+		copyYAMLLayout(oldDocument.Content[0], &newNode)
+		newNode.HeadComment = oldDocument.HeadComment + newNode.HeadComment
+		newNode.FootComment = oldDocument.FootComment + newNode.FootComment
+	}
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&newNode); err != nil {
+		return nil, err
+	}
+	err := encoder.Close()
+	return buffer.Bytes(), err
+}
+
+// copyYAMLLayout copies comments and key order from the old node to the new node where the keys match.
+func copyYAMLLayout(oldNode *yaml.Node, newNode *yaml.Node) {
+	newNode.HeadComment = oldNode.HeadComment
+	newNode.LineComment = oldNode.LineComment
+	newNode.FootComment = oldNode.FootComment
+	if oldNode.Kind == yaml.MappingNode && newNode.Kind == yaml.MappingNode {
+		oldKeys := make(map[string]int, len(oldNode.Content)/2)
+		for i := 0; i+1 < len(oldNode.Content); i += 2 {
+			oldKeys[oldNode.Content[i].Value] = i
+		}
+		type pair struct {
+			key   *yaml.Node
+			value *yaml.Node
+			order int
+		}
+		pairs := []pair{}
+		for i := 0; i+1 < len(newNode.Content); i += 2 {
+			key, value := newNode.Content[i], newNode.Content[i+1]
+			order := len(oldNode.Content) + i
+			if oldIndex, ok := oldKeys[key.Value]; ok {
+				order = oldIndex
+				copyYAMLLayout(oldNode.Content[oldIndex], key)
+				copyYAMLLayout(oldNode.Content[oldIndex+1], value)
+			}
+			pairs = append(pairs, pair{key, value, order})
+		}
+		sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].order < pairs[j].order })
+		newNode.Content = newNode.Content[:0]
+		for _, pair := range pairs {
+			newNode.Content = append(newNode.Content, pair.key, pair.value)
+		}
+	} else if oldNode.Kind == yaml.SequenceNode && newNode.Kind == yaml.SequenceNode {
+		for index := 0; index < len(oldNode.Content) && index < len(newNode.Content); index++ {
+			copyYAMLLayout(oldNode.Content[index], newNode.Content[index])
+		}
+	}
+}
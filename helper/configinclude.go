@@ -0,0 +1,142 @@
+package helper
+
+import (
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// resolveConfigInclude returns the files an entry in "include" refers to. The entry can be a path relative
+// to the including file, an absolute path, a path starting with ~/ or @project/ and it can contain globs.
+func resolveConfigInclude(from string, include string, projects map[string]string) ([]string, error) {
+	path := include
+	if strings.HasPrefix(path, "~/") {
+		usr, _ := user.Current()
+		path = usr.HomeDir + path[1:]
+	} else if strings.HasPrefix(path, "@") {
+		name, rest, _ := strings.Cut(path[1:], "/")
+		projectPath, ok := projects[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: include %q refers to the project \"%s\" which is not defined", from, include, name)
+		}
+		path = filepath.Join(projectPath, rest)
+	} else if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(from), path)
+	}
+	if strings.ContainsAny(path, "*?[") {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: include %q: %s", from, include, err)
+		}
+		files := []string{}
+		for _, match := range matches {
+			if !IsDir(match) {
+				files = append(files, match)
+			}
+		}
+		return files, nil
+	}
+	if !FileExists(path) {
+		return nil, fmt.Errorf("%s: included file %s not found", from, path)
+	}
+	return []string{path}, nil
+}
+
+func getConfigIncludes(document map[string]interface{}) []string {
+	includes := []string{}
+	switch value := document["include"].(type) {
+	case string:
+		includes = append(includes, value)
+	case []interface{}:
+		for _, include := range value {
+			if include, ok := include.(string); ok {
+				includes = append(includes, include)
+			}
+		}
+	}
+	return includes
+}
+
+func getConfigProjects(document map[string]interface{}, projects map[string]string) map[string]string {
+	merged := make(map[string]string, len(projects))
+	for name, projectPath := range projects {
+		merged[name] = projectPath
+	}
+	if projectList, ok := document["projects"].(map[string]interface{}); ok {
+		for name, project := range projectList {
+			if path, ok := projectPath(project); ok {
+				merged[name] = path
+			}
+		}
+	}
+	return merged
+}
+
+// LoadConfigWithIncludes reads a config file and the files it includes. Included files are merged first,
+// in the order they are listed, so the including file has the last word. sources is filled with the file
+// every value came from and files with every file that was read.
+func LoadConfigWithIncludes(file string, projects map[string]string, chain []string, sources map[string]string, files *[]string) (map[string]interface{}, error) {
+	absFile, _ := filepath.Abs(file)
+	for index, parent := range chain {
+		if parent == absFile {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(chain[index:], absFile), " -> "))
+		}
+	}
+	chain = append(chain, absFile)
+
+	document, err := LoadConfigDocument(file)
+	if err != nil {
+		return nil, err
+	}
+	*files = append(*files, file)
+	projects = getConfigProjects(document, projects)
+
+	merged := make(map[string]interface{})
+	for _, include := range getConfigIncludes(document) {
+		includedFiles, err := resolveConfigInclude(file, include, projects)
+		if err != nil {
+			return nil, err
+		}
+		for _, includedFile := range includedFiles {
+			includedSources := make(map[string]string)
+			included, err := LoadConfigWithIncludes(includedFile, projects, chain, includedSources, files)
+			if err != nil {
+				return nil, err
+			}
+			mergeConfigDocuments(merged, included, "", sources, includedSources, includedFile)
+		}
+	}
+	delete(document, "include")
+	mergeConfigDocuments(merged, document, "", sources, nil, file)
+	return merged, nil
+}
+
+// ExpandConfigIncludes returns the files together with every file they include. Problems with an include
+// (missing files, unknown projects and cycles) are returned as errors.
+func ExpandConfigIncludes(files []string, projects map[string]string) ([]string, []error) {
+	expanded := []string{}
+	errs := []error{}
+	seen := make(map[string]bool)
+	for _, file := range files {
+		if !FileExists(file) {
+			expanded = append(expanded, file)
+			continue
+		}
+		loaded := []string{}
+		if _, err := LoadConfigWithIncludes(file, projects, nil, make(map[string]string), &loaded); err != nil {
+			errs = append(errs, err)
+		}
+		// The file itself is first in the list, the included files follow in the order they were read
+		for _, loadedFile := range loaded {
+			if absFile, _ := filepath.Abs(loadedFile); !seen[absFile] {
+				seen[absFile] = true
+				expanded = append(expanded, loadedFile)
+			}
+		}
+		if len(loaded) == 0 {
+			expanded = append(expanded, file)
+		}
+	}
+	return expanded, errs
+}
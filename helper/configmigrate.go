@@ -0,0 +1,326 @@
+package helper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// CurrentConfigVersion is the config version written by this version of nrun. Files without a version are version 1.
+const CurrentConfigVersion = 2
+
+type configMigration struct {
+	version     int
+	description string
+	migrate     func(document map[string]interface{}, projects map[string]string) []string
+}
+
+// Migrations from one config version to the next, in order
+var configMigrations = []configMigration{
+	{2, "script names with colons instead of underscores", migrateConfigToVersion2},
+}
+
+var warnedNewerConfigFiles = make(map[string]bool)
+
+// GetConfigVersion returns the version of a config document.
+func GetConfigVersion(document map[string]interface{}) int {
+	if version, ok := document["version"].(float64); ok && version >= 1 {
+		return int(version)
+	}
+	return 1
+}
+
+func readConfigDocument(file string) (map[string]interface{}, bool) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+	jsonData, _, problem := parseConfigData(file, data)
+	if problem != nil {
+		return nil, false
+	}
+	document := make(map[string]interface{})
+	if json.Unmarshal(jsonData, &document) != nil {
+		return nil, false
+	}
+	return document, true
+}
+
+func readConfigVersion(file string) int {
+	document, ok := readConfigDocument(file)
+	if !ok {
+		return CurrentConfigVersion
+	}
+	return GetConfigVersion(document)
+}
+
+// warnNewerConfig warns (once per file) when a file was written for a newer version of nrun.
+func warnNewerConfig(file string, document map[string]interface{}) {
+	if version := GetConfigVersion(document); version > CurrentConfigVersion && !warnedNewerConfigFiles[file] {
+		warnedNewerConfigFiles[file] = true
+		fmt.Fprintf(os.Stderr, "Warning: %s uses config version %d but this nrun only knows version %d, some settings may be ignored. Please upgrade nrun.\n", file, version, CurrentConfigVersion)
+	}
+}
+
+// MigrateConfigDocument upgrades the document to the current version and returns notes about things
+// that couldn't be migrated automatically.
+func MigrateConfigDocument(document map[string]interface{}, projects map[string]string) []string {
+	notes := []string{}
+	version := GetConfigVersion(document)
+	for _, migration := range configMigrations {
+		if migration.version > version {
+			notes = append(notes, migration.migrate(document, projects)...)
+			version = migration.version
+		}
+	}
+	document["version"] = float64(version)
+	return notes
+}
+
+// configNeedsMigration reports if migrating the document to the current version changes anything but the version.
+func configNeedsMigration(document map[string]interface{}, projects map[string]string) bool {
+	if GetConfigVersion(document) >= CurrentConfigVersion {
+		return false
+	}
+	data, _ := json.Marshal(document)
+	original := make(map[string]interface{})
+	migrated := make(map[string]interface{})
+	if json.Unmarshal(data, &original) != nil || json.Unmarshal(data, &migrated) != nil {
+		return false
+	}
+	MigrateConfigDocument(migrated, projects)
+	delete(original, "version")
+	delete(migrated, "version")
+	originalData, _ := json.Marshal(original)
+	migratedData, _ := json.Marshal(migrated)
+	return string(originalData) != string(migratedData)
+}
+
+// getPackageJSONScripts returns the scripts in the package.json a path keyed config key refers to, if
+// the key refers to a single project.
+func getPackageJSONScripts(key string, projects map[string]string) map[string]string {
+	path := strings.TrimSpace(key)
+	if len(path) > 1 && path[0] == '@' {
+		path = projects[path[1:]]
+	}
+	if len(path) == 0 || isPathKeyPattern(path) || strings.Contains(path, ",") {
+		return nil
+	}
+	data, err := os.ReadFile(cleanConfigPath(path) + "/package.json")
+	if err != nil {
+		return nil
+	}
+	packageJSON := PackageJSON{}
+	if json.Unmarshal(data, &packageJSON) != nil {
+		return nil
+	}
+	return packageJSON.Scripts
+}
+
+// colonScriptName returns the name of the script in package.json that an old underscore name referred to.
+func colonScriptName(name string, scripts map[string]string) (string, bool) {
+	if !strings.Contains(name, "_") {
+		return name, false
+	}
+	if _, ok := scripts[name]; ok {
+		return name, false
+	}
+	colonName := strings.ReplaceAll(name, "_", ":")
+	if _, ok := scripts[colonName]; ok {
+		return colonName, true
+	}
+	return name, false
+}
+
+func migrateConfigToVersion2(document map[string]interface{}, projects map[string]string) []string {
+	notes := []string{}
+	projects = getConfigProjects(document, projects)
+
+	// Script names used to have their colons replaced with underscores
+	if env, ok := document["env"].(map[string]interface{}); ok {
+		for _, pathKey := range sortedKeys(env) {
+			scripts, ok := env[pathKey].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			packageScripts := getPackageJSONScripts(pathKey, projects)
+			for _, name := range sortedKeys(scripts) {
+				if !strings.Contains(name, "_") {
+					continue
+				}
+				if packageScripts == nil {
+					notes = append(notes, appendJSONPath(appendJSONPath("env", pathKey), name)+": can't tell if the underscores used to be colons, please check it")
+					continue
+				}
+				if colonName, ok := colonScriptName(name, packageScripts); ok {
+					if _, exists := scripts[colonName]; !exists {
+						scripts[colonName] = scripts[name]
+						delete(scripts, name)
+					}
+				}
+			}
+		}
+	}
+	if paths, ok := document["path"].(map[string]interface{}); ok {
+		for _, pathKey := range sortedKeys(paths) {
+			aliases, ok := paths[pathKey].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			packageScripts := getPackageJSONScripts(pathKey, projects)
+			for _, alias := range sortedKeys(aliases) {
+				script, ok := aliases[alias].(string)
+				if !ok || packageScripts == nil {
+					continue
+				}
+				if colonName, ok := colonScriptName(script, packageScripts); ok {
+					aliases[alias] = colonName
+				}
+			}
+		}
+	}
+
+	return notes
+}
+
+// diffLines returns a simple line based diff of the two texts, - for removed lines and + for added lines.
+func diffLines(oldText string, newText string) []string {
+	oldLines := strings.Split(strings.TrimRight(oldText, "\n"), "\n")
+	newLines := strings.Split(strings.TrimRight(newText, "\n"), "\n")
+	lengths := make([][]int, len(oldLines)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+	diff := []string{}
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			diff = append(diff, "  "+oldLines[i])
+			i++
+			j++
+		case j < len(newLines) && (i == len(oldLines) || lengths[i][j+1] >= lengths[i+1][j]):
+			diff = append(diff, "+ "+newLines[j])
+			j++
+		default:
+			diff = append(diff, "- "+oldLines[i])
+			i++
+		}
+	}
+	return diff
+}
+
+// ConfigMigrate upgrades the global config files (or the closest local one with --local) to the current
+// version. The changes are shown and have to be confirmed unless --yes is given.
+func ConfigMigrate(flagList *FlagList) error {
+	files := GetGlobalConfigFiles()
+	if flagList.ConfigLocal != nil && *flagList.ConfigLocal {
+		files = []string{GetLocalConfigTarget()}
+	}
+	projects := map[string]string{}
+	for _, file := range GetGlobalConfigFiles() {
+		if data, err := os.ReadFile(file); err == nil {
+			if jsonData, _, problem := parseConfigData(file, data); problem == nil {
+				document := make(map[string]interface{})
+				if json.Unmarshal(jsonData, &document) == nil {
+					projects = getConfigProjects(document, projects)
+				}
+			}
+		}
+	}
+
+	migrated := 0
+	for _, file := range files {
+		if !FileExists(file) {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		jsonData, _, problem := parseConfigData(file, data)
+		if problem != nil {
+			return errors.New(problem.String())
+		}
+		document := make(map[string]interface{})
+		if err := json.Unmarshal(jsonData, &document); err != nil {
+			return err
+		}
+		version := GetConfigVersion(document)
+		if version > CurrentConfigVersion {
+			return fmt.Errorf("%s uses config version %d which is newer than this nrun (%d)", file, version, CurrentConfigVersion)
+		}
+		if version == CurrentConfigVersion {
+			fmt.Println(file, "already uses config version", CurrentConfigVersion)
+			continue
+		}
+		if !configNeedsMigration(document, projects) {
+			fmt.Println(file, "doesn't need to be migrated")
+			continue
+		}
+
+		notes := MigrateConfigDocument(document, projects)
+		migratedData, _ := json.Marshal(document)
+		config := Config{}
+		if err := json.Unmarshal(migratedData, &config); err != nil {
+			return fmt.Errorf("%s can't be migrated: %s", file, err)
+		}
+		for _, problem := range CheckConfigData("", migratedData, map[string]string{}) {
+			if problem.IsError {
+				return fmt.Errorf("%s can't be migrated: %s: %s", file, problem.Path, problem.Message)
+			}
+		}
+		newData, err := EncodeConfig(file, &config)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Migrating %s from config version %d to %d:\n", file, version, CurrentConfigVersion)
+		for _, migration := range configMigrations {
+			if migration.version > version {
+				fmt.Printf("  version %d: %s\n", migration.version, migration.description)
+			}
+		}
+		for _, line := range diffLines(string(data), string(newData)) {
+			fmt.Println(line)
+		}
+		sort.Strings(notes)
+		for _, note := range notes {
+			fmt.Println("Note:", note)
+		}
+
+		if flagList.AssumeYes == nil || !*flagList.AssumeYes {
+			if !IsTerminal(os.Stdin) {
+				fmt.Println("Nothing changed, use --yes to migrate without a terminal")
+				continue
+			}
+			if answer := strings.ToLower(AskQuestion("Migrate " + file + "? [y/N] ")); answer != "y" && answer != "yes" {
+				fmt.Println("Skipped", file)
+				continue
+			}
+		}
+		if err := WriteConfig(file, &config); err != nil {
+			return err
+		}
+		migrated++
+		fmt.Println("Migrated", file, "(a backup was saved, see nrun --config-restore list)")
+	}
+	if migrated == 0 && len(files) > 0 && (flagList.BeVerbose != nil && *flagList.BeVerbose) {
+		fmt.Println("No files were migrated")
+	}
+	return nil
+}
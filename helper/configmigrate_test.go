@@ -0,0 +1,47 @@
+package helper
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMigrateConfigDocument(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(dir+"/package.json", []byte(`{"scripts":{"test:unit":"jest","build":"tsc"}}`), 0644)
+
+	// A version 1 file that doesn't use the old script names only gets a version
+	clean := `{"projects":{"api":"` + dir + `"},"env":{"@api":{"build":"NODE_ENV=production"}},"path":{"@api":{"b":"build"}},
+		"scripts":{"deploy":["npm run build"]},"package.json":{"@api":{"scripts":{"lint":"eslint ."}}}}`
+	document := make(map[string]interface{})
+	expected := make(map[string]interface{})
+	json.Unmarshal([]byte(clean), &document)
+	json.Unmarshal([]byte(clean), &expected)
+	if configNeedsMigration(document, nil) {
+		t.Errorf("configNeedsMigration() = true for a file without old script names")
+	}
+	if notes := MigrateConfigDocument(document, nil); len(notes) != 0 {
+		t.Errorf("MigrateConfigDocument() notes = %q, expected none", notes)
+	}
+	expected["version"] = float64(CurrentConfigVersion)
+	if !reflect.DeepEqual(document, expected) {
+		t.Errorf("MigrateConfigDocument() = %v, expected %v", document, expected)
+	}
+
+	// Underscores are only turned into colons when the package.json has the script
+	old := `{"projects":{"api":"` + dir + `"},"env":{"@api":{"test_unit":"DEBUG=1","build_all":"X=1"},"*":{"x_y":"Y=1"}},"path":{"@api":{"t":"test_unit"}}}`
+	document = make(map[string]interface{})
+	json.Unmarshal([]byte(old), &document)
+	if !configNeedsMigration(document, nil) {
+		t.Errorf("configNeedsMigration() = false for a file with old script names")
+	}
+	notes := MigrateConfigDocument(document, nil)
+	data, _ := json.Marshal(document)
+	if expected := `{"env":{"*":{"x_y":"Y=1"},"@api":{"build_all":"X=1","test:unit":"DEBUG=1"}},"path":{"@api":{"t":"test:unit"}},"projects":{"api":"` + dir + `"},"version":2}`; string(data) != expected {
+		t.Errorf("MigrateConfigDocument() = %s, expected %s", data, expected)
+	}
+	if len(notes) != 1 {
+		t.Errorf("expected a note about env[\"*\"].x_y, got %q", notes)
+	}
+}
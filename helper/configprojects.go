@@ -0,0 +1,122 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// A project in "projects" is either just its path or an object with the path and more information about the
+// project. Config.Projects always holds the paths, the rest is kept in Config.ProjectDetails.
+
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type plainConfig Config
+	aux := struct {
+		*plainConfig
+		Projects map[string]json.RawMessage `json:"projects,omitempty"`
+	}{plainConfig: (*plainConfig)(c)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	c.Projects = nil
+	c.ProjectDetails = nil
+	if aux.Projects == nil {
+		return nil
+	}
+	c.Projects = make(map[string]string, len(aux.Projects))
+	for name, raw := range aux.Projects {
+		raw = bytes.TrimSpace(raw)
+		if len(raw) > 0 && raw[0] == '{' {
+			details := ProjectStruct{}
+			if err := json.Unmarshal(raw, &details); err != nil {
+				return fmt.Errorf("projects.%s: %s", name, err)
+			}
+			if c.ProjectDetails == nil {
+				c.ProjectDetails = make(map[string]ProjectStruct)
+			}
+			c.Projects[name] = details.Path
+			c.ProjectDetails[name] = details
+			continue
+		}
+		path := ""
+		if err := json.Unmarshal(raw, &path); err != nil {
+			return fmt.Errorf("projects.%s: expected the path of the project or an object", name)
+		}
+		c.Projects[name] = path
+	}
+	return nil
+}
+
+func (c Config) MarshalJSON() ([]byte, error) {
+	type plainConfig Config
+	var projects map[string]interface{}
+	if c.Projects != nil {
+		projects = make(map[string]interface{}, len(c.Projects))
+		for name, path := range c.Projects {
+			if details, ok := c.ProjectDetails[name]; ok {
+				details.Path = path
+				projects[name] = details
+			} else {
+				projects[name] = path
+			}
+		}
+	}
+	return json.Marshal(struct {
+		plainConfig
+		Projects map[string]interface{} `json:"projects,omitempty"`
+	}{plainConfig(c), projects})
+}
+
+// projectPath returns the path of a project in a config document, where it can be a string or an object.
+func projectPath(value interface{}) (string, bool) {
+	switch project := value.(type) {
+	case string:
+		return project, true
+	case map[string]interface{}:
+		path, ok := project["path"].(string)
+		return path, ok
+	}
+	return "", false
+}
+
+// GetProjectDetails returns the information about the project, the path is always set.
+func GetProjectDetails(name string) (ProjectStruct, bool) {
+	config, err := GetEffectiveConfig("")
+	if err != nil {
+		return ProjectStruct{}, false
+	}
+	path, ok := config.Projects[name]
+	if !ok {
+		return ProjectStruct{}, false
+	}
+	details := config.ProjectDetails[name]
+	details.Path = path
+	return details, true
+}
+
+// getProjectAtPath returns the name of the project registered with exactly this path.
+func getProjectAtPath(path string, projects map[string]string) string {
+	path = cleanConfigPath(path)
+	found := ""
+	for name, projectPath := range projects {
+		if cleanConfigPath(projectPath) == path && (len(found) == 0 || name < found) {
+			found = name
+		}
+	}
+	return found
+}
+
+// GetPackageManager returns the package manager to use in the path, the one preferred by the project or npm.
+func GetPackageManager(path string) string {
+	config, err := GetEffectiveConfig("")
+	if err != nil {
+		return "npm"
+	}
+	if name := getProjectAtPath(path, config.Projects); len(name) > 0 {
+		if manager := strings.TrimSpace(config.ProjectDetails[name].PackageManager); len(manager) > 0 {
+			return manager
+		}
+	}
+	return "npm"
+}
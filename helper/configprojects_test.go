@@ -0,0 +1,85 @@
+package helper
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestConfigProjectForms(t *testing.T) {
+	data := []byte(`{"projects":{"api":{"path":"/dev/api","script":"dev","tags":["backend"]},"web":"/dev/web"}}`)
+	config := Config{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]string{"api": "/dev/api", "web": "/dev/web"}; !reflect.DeepEqual(config.Projects, expected) {
+		t.Errorf("Projects = %v, expected %v", config.Projects, expected)
+	}
+	if details := config.ProjectDetails["api"]; details.Script != "dev" || !reflect.DeepEqual(details.Tags, []string{"backend"}) {
+		t.Errorf("ProjectDetails[api] = %+v", details)
+	}
+	if _, ok := config.ProjectDetails["web"]; ok {
+		t.Errorf("web should only have a path")
+	}
+
+	// The path in Projects is the one that's written, the rest of the object is kept
+	config.Projects["api"] = "/dev/api2"
+	encoded, err := json.Marshal(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"projects":{"api":{"path":"/dev/api2","tags":["backend"],"script":"dev"},"web":"/dev/web"}}`
+	if string(encoded) != expected {
+		t.Errorf("Marshal() = %s, expected %s", encoded, expected)
+	}
+
+	if err := json.Unmarshal([]byte(`{"projects":{"api":1}}`), &Config{}); err == nil {
+		t.Errorf("expected an error for a project that is a number")
+	}
+}
+
+// useTestConfig makes the config in data the global config while the test runs.
+func useTestConfig(t *testing.T, data string) {
+	dir := t.TempDir()
+	os.MkdirAll(dir+"/nrun", 0755)
+	if err := os.WriteFile(dir+"/nrun/config.json", []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	effectiveConfigCache = make(map[string]map[string]interface{}, 10)
+	t.Cleanup(func() {
+		effectiveConfigCache = make(map[string]map[string]interface{}, 10)
+	})
+}
+
+func TestGetProjectDetails(t *testing.T) {
+	useTestConfig(t, `{"projects":{
+		"nrun-test-api":{"path":"/dev/nrun-test/api","script":"dev","packagemanager":"pnpm"},
+		"nrun-test-web":"/dev/nrun-test/web/"}}`)
+
+	details, ok := GetProjectDetails("nrun-test-api")
+	if !ok || details.Path != "/dev/nrun-test/api" || details.Script != "dev" {
+		t.Errorf("GetProjectDetails(api) = %+v, %v", details, ok)
+	}
+	// A project given as just its path has details with only the path
+	details, ok = GetProjectDetails("nrun-test-web")
+	if expected := (ProjectStruct{Path: "/dev/nrun-test/web/"}); !ok || !reflect.DeepEqual(details, expected) {
+		t.Errorf("GetProjectDetails(web) = %+v, %v, expected %+v", details, ok, expected)
+	}
+	if _, ok := GetProjectDetails("nrun-test-missing"); ok {
+		t.Errorf("GetProjectDetails(missing) should not find a project")
+	}
+
+	tests := map[string]string{
+		"/dev/nrun-test/api":  "pnpm",
+		"/dev/nrun-test/api/": "pnpm",
+		"/dev/nrun-test/web":  "npm",
+		"/dev/nrun-test":      "npm",
+	}
+	for path, expected := range tests {
+		if result := GetPackageManager(path); result != expected {
+			t.Errorf("GetPackageManager(%q) = %q, expected %q", path, result, expected)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Number of timestamped backups kept for every config file. Can be changed with NRUN_CONFIG_BACKUPS.
+const defaultConfigBackups = 10
+
+const configBackupTimeFormat = "20060102-150405.000"
+
+var configBackupRegexp = regexp.MustCompile(`\.[0-9]{8}-[0-9]{6}\.[0-9]{3}\.bak$`)
+
+// ErrConfigUnchanged can be returned from the function given to UpdateConfig to skip writing the config.
+var ErrConfigUnchanged = errors.New("config unchanged")
+
+// LockConfigFile takes an advisory lock for the config file, waiting until no other nrun process holds it.
+// The lock is held on a separate .lock file since the config file itself is replaced when it's written.
+func LockConfigFile(filename string) (func(), error) {
+	lock, err := os.OpenFile(filename+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(lock); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return func() {
+		_ = unlockFile(lock)
+		lock.Close()
+	}, nil
+}
+
+// UpdateConfig reads the config file while holding the lock, lets update change it and writes it back.
+// This way changes made by another nrun process between reading and writing aren't lost.
+func UpdateConfig(filename string, update func(config *Config) error) error {
+	unlock, err := LockConfigFile(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	delete(configCache, filename)
+	config, err := ReadConfig(filename)
+	if err != nil {
+		if err.Error() != "config file not found" {
+			return err
+		}
+		config = &Config{Version: CurrentConfigVersion}
+	}
+	if err := update(config); err != nil {
+		if err == ErrConfigUnchanged {
+			return nil
+		}
+		return err
+	}
+	return writeConfig(filename, config)
+}
+
+func writeConfig(filename string, config *Config) error {
+	// Settings this nrun doesn't know about would be lost
+	if version := readConfigVersion(filename); version > CurrentConfigVersion {
+		return fmt.Errorf("%s uses config version %d which is newer than this nrun (%d), please upgrade nrun to change it", filename, version, CurrentConfigVersion)
+	}
+	data, err := EncodeConfig(filename, config)
+	if err != nil {
+		return err
+	}
+	if err := BackupConfigFile(filename); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filename, data); err != nil {
+		return err
+	}
+	configCache[filename] = config
+	effectiveConfigCache = make(map[string]map[string]interface{}, 10)
+	return nil
+}
+
+// writeFileAtomic writes the data to a temporary file in the same directory and renames it over the file,
+// so the file is never left half written. Symlinks (e.g. from a dotfiles repository) are followed.
+func writeFileAtomic(filename string, data []byte) error {
+	if target, err := filepath.EvalSymlinks(filename); err == nil {
+		filename = target
+	}
+	mode := os.FileMode(0644)
+	if stat, err := os.Stat(filename); err == nil {
+		mode = stat.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpName, mode)
+	}
+	if err == nil {
+		err = os.Rename(tmpName, filename)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if dir, err := os.Open(filepath.Dir(filename)); err == nil {
+		_ = dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+func getConfigBackupCount() int {
+	if value := os.Getenv("NRUN_CONFIG_BACKUPS"); len(value) > 0 {
+		if count, err := strconv.Atoi(value); err == nil && count >= 0 {
+			return count
+		}
+	}
+	return defaultConfigBackups
+}
+
+// GetConfigBackups returns the backups of the config file, newest first.
+func GetConfigBackups(filename string) []string {
+	matches, _ := filepath.Glob(filename + ".*.bak")
+	backups := []string{}
+	for _, match := range matches {
+		if configBackupRegexp.MatchString(match[len(filename):]) {
+			backups = append(backups, match)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups
+}
+
+// BackupConfigFile saves a timestamped copy of the config file and removes the oldest backups.
+func BackupConfigFile(filename string) error {
+	count := getConfigBackupCount()
+	if !FileExists(filename) || count == 0 {
+		return nil
+	}
+	backup := filename + "." + time.Now().Format(configBackupTimeFormat) + ".bak"
+	if err := CopyFile(filename, backup); err != nil {
+		return err
+	}
+	backups := GetConfigBackups(filename)
+	for index := count; index < len(backups); index++ {
+		os.Remove(backups[index])
+	}
+	return nil
+}
+
+// RestoreConfig replaces the config file with one of its backups, 1 being the newest. The current file is
+// backed up first so a restore can be undone by restoring again.
+func RestoreConfig(args []string, flagList *FlagList) error {
+	filename := GetGlobalConfigFile()
+	if flagList.ConfigLocal != nil && *flagList.ConfigLocal {
+		filename = GetLocalConfigTarget()
+	}
+	backups := GetConfigBackups(filename)
+	if len(args) > 0 && args[0] == "list" {
+		if len(backups) == 0 {
+			fmt.Println("There are no backups of", filename)
+		}
+		for index, backup := range backups {
+			fmt.Printf("%3d  %s\n", index+1, backup)
+		}
+		return nil
+	}
+	number := 1
+	if len(args) > 0 {
+		var err error
+		if number, err = strconv.Atoi(args[0]); err != nil || number < 1 {
+			return errors.New("usage: nrun --config-restore [list|n]")
+		}
+	}
+	if number > len(backups) {
+		return fmt.Errorf("%s has %d backup(s)", filename, len(backups))
+	}
+	backup := backups[number-1]
+	data, err := os.ReadFile(backup)
+	if err != nil {
+		return err
+	}
+	if _, _, problem := parseConfigData(filename, data); problem != nil {
+		return errors.New("the backup is not valid: " + problem.Message)
+	}
+
+	unlock, err := LockConfigFile(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := BackupConfigFile(filename); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filename, data); err != nil {
+		return err
+	}
+	delete(configCache, filename)
+	effectiveConfigCache = make(map[string]map[string]interface{}, 10)
+	fmt.Println("Restored", filename, "from", backup)
+	return nil
+}
@@ -0,0 +1,98 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Ranges using these prefixes point at something other than a registry version and can't be checked
+var nonSemVerDependencyPrefixes = []string{"file:", "link:", "git", "http:", "https:", "npm:", "workspace:", "github:", "portal:"}
+
+func ShowDependencyConsistency(packageJSON PackageJSON, path string, flagList *FlagList) int {
+	dependencies := make(map[string]string, len(packageJSON.Dependencies)+len(packageJSON.DevDependencies))
+	for name, versionRange := range packageJSON.DevDependencies {
+		dependencies[name] = versionRange
+	}
+	for name, versionRange := range packageJSON.Dependencies {
+		dependencies[name] = versionRange
+	}
+	names := make([]string, 0, len(dependencies))
+	for name := range dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	missing, mismatched, skipped, ok := 0, 0, 0, 0
+	for _, name := range names {
+		versionRange := dependencies[name]
+		if isNonSemVerDependency(versionRange) {
+			skipped++
+			if flagList.BeVerbose != nil && *flagList.BeVerbose {
+				fmt.Printf("  skipped    %s (%s)\n", name, versionRange)
+			}
+			continue
+		}
+		installed := GetInstalledVersion(path, name)
+		if installed == "" {
+			missing++
+			fmt.Printf("  missing    %s (wants %s)\n", name, versionRange)
+			continue
+		}
+		satisfies, err := SemVerSatisfies(installed, versionRange)
+		if err != nil {
+			skipped++
+			fmt.Printf("  unknown    %s %s (%s)\n", name, installed, err)
+			continue
+		}
+		if !satisfies {
+			mismatched++
+			fmt.Printf("  mismatch   %s %s (wants %s)\n", name, installed, versionRange)
+			continue
+		}
+		ok++
+		if flagList.BeVerbose != nil && *flagList.BeVerbose {
+			fmt.Printf("  ok         %s %s (wants %s)\n", name, installed, versionRange)
+		}
+	}
+	fmt.Printf("%d dependencies: %d ok, %d mismatched, %d missing, %d skipped\n", len(names), ok, mismatched, missing, skipped)
+	if missing > 0 || mismatched > 0 {
+		return 1
+	}
+	return 0
+}
+
+func isNonSemVerDependency(versionRange string) bool {
+	for _, prefix := range nonSemVerDependencyPrefixes {
+		if strings.HasPrefix(versionRange, prefix) {
+			return true
+		}
+	}
+	return strings.Contains(versionRange, "/")
+}
+
+// GetInstalledVersion looks for the installed package in node_modules, walking upwards like node does.
+func GetInstalledVersion(path string, name string) string {
+	for dir := path; len(dir) > 0; {
+		packageFile := dir + "/node_modules/" + name + "/package.json"
+		if FileExists(packageFile) {
+			file, err := os.ReadFile(packageFile)
+			if err != nil {
+				return ""
+			}
+			packageJSON := PackageJSON{}
+			if json.Unmarshal(file, &packageJSON) != nil {
+				return ""
+			}
+			return packageJSON.Version
+		}
+		index := strings.LastIndex(dir, "/")
+		if index < 0 {
+			break
+		}
+		dir = dir[:index]
+	}
+	return ""
+}
@@ -0,0 +1,183 @@
+package helper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type discoveredProject struct {
+	name string
+	path string
+}
+
+// findProjectCandidates walks the tree looking for directories with a package.json (and git repositories if
+// withGit is set). node_modules and hidden directories are skipped, depth is the number of levels below dir.
+func findProjectCandidates(dir string, depth int, withGit bool) ([]string, error) {
+	candidates := []string{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Directories that can't be read are skipped
+			if entry != nil && entry.IsDir() && path != dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if path != dir && (entry.Name() == "node_modules" || strings.HasPrefix(entry.Name(), ".")) {
+			return filepath.SkipDir
+		}
+		if FileExists(path+"/package.json") || (withGit && FileExists(path+"/.git")) {
+			candidates = append(candidates, path)
+		}
+		if relative, _ := filepath.Rel(dir, path); relative != "." && strings.Count(relative, string(filepath.Separator))+1 >= depth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return candidates, err
+}
+
+// proposeProjectName uses the name in package.json without the scope, or the name of the directory.
+func proposeProjectName(path string) string {
+	if data, err := os.ReadFile(path + "/package.json"); err == nil {
+		packageJSON := PackageJSON{}
+		if json.Unmarshal(data, &packageJSON) == nil && len(packageJSON.Name) > 0 {
+			name := packageJSON.Name
+			if index := strings.LastIndex(name, "/"); index >= 0 {
+				name = name[index+1:]
+			}
+			if len(name) > 0 {
+				return name
+			}
+		}
+	}
+	return filepath.Base(path)
+}
+
+// DiscoverProjects finds projects below dir and registers them in the global config. Every project has to be
+// confirmed (or renamed) unless --yes is given, then projects whose name is already taken are skipped.
+func DiscoverProjects(dir string, flagList *FlagList) error {
+	depth := 3
+	if flagList.DiscoverDepth != nil && *flagList.DiscoverDepth > 0 {
+		depth = *flagList.DiscoverDepth
+	}
+	dir, err := filepath.Abs(ExpandHome(dir))
+	if err != nil {
+		return err
+	}
+	if stat, err := os.Stat(dir); err != nil || !stat.IsDir() {
+		return errors.New("The path \"" + dir + "\" is not a directory")
+	}
+	candidates, err := findProjectCandidates(dir, depth, flagList.DiscoverGit != nil && *flagList.DiscoverGit)
+	if err != nil {
+		return err
+	}
+
+	existing := map[string]string{}
+	if config, err := GetEffectiveConfig(""); err == nil && config.Projects != nil {
+		existing = config.Projects
+	}
+	registered := map[string]string{}
+	for name, path := range existing {
+		registered[cleanConfigPath(path)] = name
+	}
+
+	assumeYes := flagList.AssumeYes != nil && *flagList.AssumeYes
+	interactive := !assumeYes && IsTerminal(os.Stdin)
+	taken := func(name string, projects []discoveredProject) string {
+		if path, ok := existing[name]; ok {
+			return path
+		}
+		for _, project := range projects {
+			if project.name == name {
+				return project.path
+			}
+		}
+		return ""
+	}
+
+	projects := []discoveredProject{}
+	for _, path := range candidates {
+		if name, ok := registered[path]; ok {
+			fmt.Printf("%s is already registered as %s\n", path, name)
+			continue
+		}
+		name := proposeProjectName(path)
+		if !interactive {
+			if other := taken(name, projects); len(other) > 0 {
+				fmt.Printf("Conflict: %s can't be added as %s, the name is already used for %s\n", path, name, other)
+				continue
+			}
+			fmt.Printf("Found %s : %s\n", name, path)
+			projects = append(projects, discoveredProject{name, path})
+			continue
+		}
+		for {
+			if other := taken(name, projects); len(other) > 0 {
+				fmt.Printf("The name %s is already used for %s\n", name, other)
+				name = strings.TrimSpace(AskQuestion("Name for " + path + " (empty to skip): "))
+				if len(name) == 0 {
+					break
+				}
+				continue
+			}
+			answer := strings.TrimSpace(AskQuestion("Add " + path + " as " + name + "? [Y/n/other name] "))
+			switch strings.ToLower(answer) {
+			case "", "y", "yes":
+				projects = append(projects, discoveredProject{name, path})
+			case "n", "no":
+			default:
+				name = answer
+				continue
+			}
+			break
+		}
+	}
+
+	if len(projects) == 0 {
+		fmt.Println("No new projects found")
+		return nil
+	}
+	if !assumeYes && !interactive {
+		fmt.Println("Nothing changed, use --yes to register the projects without a terminal")
+		return nil
+	}
+	added := []string{}
+	err = UpdateConfig(GetGlobalConfigFile(), func(config *Config) error {
+		if config.Projects == nil {
+			config.Projects = make(map[string]string)
+		}
+		added = added[:0]
+		for _, project := range projects {
+			// Another nrun process might have registered the name while we were asking
+			if other, ok := config.Projects[project.name]; ok && other != project.path {
+				fmt.Printf("Conflict: %s can't be added as %s, the name is already used for %s\n", project.path, project.name, other)
+				continue
+			}
+			config.Projects[project.name] = project.path
+			added = append(added, project.name)
+		}
+		if len(added) == 0 {
+			return ErrConfigUnchanged
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(added)
+	if len(added) == 1 {
+		fmt.Println("Added 1 project:", added[0])
+	} else if len(added) > 1 {
+		fmt.Println("Added", len(added), "projects:", strings.Join(added, ", "))
+	}
+	return nil
+}
@@ -80,7 +80,7 @@ func DummyCode() {
 
 }
 
-func VersionInformation() {
+func VersionInformation(args []string) {
 	fmt.Println("Version information:")
 
 	fmt.Println("NVM (Node Version Manager")
@@ -95,8 +95,23 @@ func VersionInformation() {
 	versions, err2 := GetLatestNodeJSRelease()
 	if err2 != nil {
 		fmt.Println("  Error getting latest Node.js release:", err2)
-	} else {
-		nodeVersion := versions[0]
-		fmt.Println("  Latest Node.js release:", nodeVersion.Version)
+		return
+	}
+	nodeVersion := versions[0]
+	fmt.Println("  Latest Node.js release:", nodeVersion.Version)
+	if latestLTS := FilterNodeJSReleases(versions, "", true); len(latestLTS) > 0 {
+		fmt.Println("  Latest Node.js LTS release:", latestLTS[0].Version, "("+fmt.Sprint(latestLTS[0].Lts)+")")
+	}
+	// Each argument is a semver range, e.g. nrun -vi "^18" ">=20 <22"
+	for _, arg := range args {
+		matching := FilterNodeJSReleases(versions, arg, false)
+		if len(matching) == 0 {
+			fmt.Println("  No Node.js release matches", "\""+arg+"\"")
+			continue
+		}
+		fmt.Println("  Latest Node.js release matching", "\""+arg+"\":", matching[0].Version)
+		if matchingLTS := FilterNodeJSReleases(matching, "", true); len(matchingLTS) > 0 && matchingLTS[0].Version != matching[0].Version {
+			fmt.Println("  Latest Node.js LTS release matching", "\""+arg+"\":", matchingLTS[0].Version)
+		}
 	}
 }
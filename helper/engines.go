@@ -0,0 +1,156 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type RequirementMismatch struct {
+	Tool      string
+	Required  string
+	Installed string
+	Message   string
+}
+
+// Arguments used to ask a tool for its version. Tools not listed here are asked with --version.
+var toolVersionArgs = map[string][]string{
+	"node": {"-v"},
+	"npm":  {"-v"},
+	"go":   {"version"},
+	"php":  {"-v"},
+	"ruby": {"-v"},
+	"make": {"-v"},
+	"zig":  {"version"},
+}
+
+var toolVersionCache = make(map[string]string, 10)
+
+func GetToolVersion(tool string) string {
+	if version, ok := toolVersionCache[tool]; ok {
+		return version
+	}
+	args, ok := toolVersionArgs[tool]
+	if !ok {
+		args = []string{"--version"}
+	}
+	version := ""
+	if output := GetVersionFromExecutable(tool, args); output != "" {
+		cleanVersion := regexp.MustCompile(`v?([0-9]+(\.[0-9]+)*)`).FindStringSubmatch(output)
+		if len(cleanVersion) > 1 {
+			version = cleanVersion[1]
+		}
+	}
+	toolVersionCache[tool] = version
+	return version
+}
+
+func ParseRequirement(requirement string) (string, string) {
+	requirement = strings.TrimSpace(requirement)
+	index := strings.IndexAny(requirement, "<>=^~ ")
+	if index < 0 {
+		return requirement, ""
+	}
+	return strings.TrimSpace(requirement[:index]), strings.TrimSpace(requirement[index:])
+}
+
+func VersionSatisfies(version string, constraint string) (bool, error) {
+	constraint = strings.TrimSpace(constraint)
+	if constraint == "" {
+		return true, nil
+	}
+	return SemVerSatisfies(version, constraint)
+}
+
+func checkRequirement(tool string, constraint string) *RequirementMismatch {
+	if _, err := exec.LookPath(tool); err != nil {
+		return &RequirementMismatch{Tool: tool, Required: constraint, Message: "is not installed"}
+	}
+	if constraint == "" {
+		return nil
+	}
+	installed := GetToolVersion(tool)
+	if installed == "" {
+		return &RequirementMismatch{Tool: tool, Required: constraint, Message: "version could not be determined"}
+	}
+	ok, err := VersionSatisfies(installed, constraint)
+	if err != nil {
+		return &RequirementMismatch{Tool: tool, Required: constraint, Installed: installed, Message: err.Error()}
+	}
+	if !ok {
+		return &RequirementMismatch{Tool: tool, Required: constraint, Installed: installed, Message: "does not satisfy the requirement"}
+	}
+	return nil
+}
+
+func CheckRequirements(packageJSON PackageJSON, path string) []RequirementMismatch {
+	mismatches := []RequirementMismatch{}
+	for _, tool := range []string{"node", "npm"} {
+		if constraint, ok := packageJSON.Engines[tool]; ok {
+			if mismatch := checkRequirement(tool, constraint); mismatch != nil {
+				mismatches = append(mismatches, *mismatch)
+			}
+		}
+	}
+	for _, requirement := range GetRequirements(path) {
+		tool, constraint := ParseRequirement(requirement)
+		if len(tool) == 0 {
+			continue
+		}
+		if mismatch := checkRequirement(tool, constraint); mismatch != nil {
+			mismatches = append(mismatches, *mismatch)
+		}
+	}
+	return mismatches
+}
+
+func GetRequirements(path string) []string {
+	config, err := GetEffectiveConfig(path)
+	if err != nil {
+		return []string{}
+	}
+	return config.Requires
+}
+
+func GetEngineCheckMode(path string) string {
+	mode := "warn"
+	if config, err := GetEffectiveConfig(path); err == nil && config.EngineCheck != "" {
+		mode = config.EngineCheck
+	}
+	return strings.ToLower(mode)
+}
+
+// RunRequirementCheck reports engine and toolchain mismatches. An error is only returned when
+// the check mode is "block" and at least one requirement isn't met.
+func RunRequirementCheck(packageJSON PackageJSON, path string, flagList *FlagList) error {
+	mode := GetEngineCheckMode(path)
+	if mode == "off" {
+		return nil
+	}
+	mismatches := CheckRequirements(packageJSON, path)
+	if len(mismatches) == 0 {
+		if flagList.BeVerbose != nil && *flagList.BeVerbose {
+			fmt.Println("All engine and toolchain requirements are met")
+		}
+		return nil
+	}
+	for _, mismatch := range mismatches {
+		line := mismatch.Tool
+		if mismatch.Required != "" {
+			line += " " + mismatch.Required
+		}
+		line += " " + mismatch.Message
+		if mismatch.Installed != "" {
+			line += " (installed version is " + mismatch.Installed + ")"
+		}
+		log.Println("Requirement:", line)
+	}
+	if mode == "block" {
+		return errors.New(strconv.Itoa(len(mismatches)) + " requirement(s) not met, aborting (set \"enginecheck\" to \"warn\" to run anyway)")
+	}
+	return nil
+}
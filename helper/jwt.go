@@ -10,7 +10,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"os/user"
 	"strings"
 	"time"
 )
@@ -132,13 +131,11 @@ func SignJWTToken(args []string) error {
 		// Print the token
 		fmt.Println(header + "." + payload + "." + signature)
 	} else if len(args) == 2 {
-		usr, _ := user.Current()
-		dir := usr.HomeDir
 		filename := args[1]
 
 		if FileExists(filename) == false {
-			config, _ := ReadConfig(dir + "/.nrun.json")
-			if config.TokenTemplates[filename] == "" {
+			config, _ := GetEffectiveConfig("")
+			if config == nil || config.TokenTemplates[filename] == "" {
 				return errors.New("Token template not found")
 			}
 		}
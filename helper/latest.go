@@ -2,9 +2,8 @@ package helper
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
-	"strconv"
-	"strings"
 )
 
 type NodeJSRelease struct {
@@ -29,46 +28,46 @@ func VersionCompare(v1, v2 string) int {
 	// Returns 0 if v1 == v2
 	// Returns -1 if v1 < v2
 	// Returns -2 if v1 or v2 is not a valid version string
-	// v1 and v2 must be in the format "vX.Y.Z" or "X.Y.Z"
-	// where X, Y and Z are integers
-	// e.g. "v1.2.3" or "1.2.3"
-	// The "v" prefix is optional
-	// The version strings must have the same number of components
-	// e.g. "v1.2.3" and "v1.2.3.4" are not valid
-	// e.g. "v1.2.3" and "v1.2" are not valid
-	// e.g. "v1.2.3" and "v1.2.3.0" are valid
-	// e.g. "v1.2.3" and "v1.2.3" are valid
-	// e.g. "v1.2.3" and "v1.
-	v1Parts := strings.Split(v1, ".")
-	v2Parts := strings.Split(v2, ".")
-	for i := 0; i < len(v1Parts); i++ {
-		if i >= len(v2Parts) {
-			return -2
-		}
-		v1Part := v1Parts[i]
-		v2Part := v2Parts[i]
-		if v1Part[0] == 'v' {
-			v1Part = v1Part[1:]
-		}
-		if v2Part[0] == 'v' {
-			v2Part = v2Part[1:]
-		}
-		v1Int, err := strconv.Atoi(v1Part)
-		if err != nil {
-			return -2
-		}
-		v2Int, err := strconv.Atoi(v2Part)
+	// The versions are parsed as semver, so "v18" equals "18.0.0" and "1.2.3-beta" is less than "1.2.3"
+	semVer1, err := ParseSemVer(v1)
+	if err != nil {
+		return -2
+	}
+	semVer2, err := ParseSemVer(v2)
+	if err != nil {
+		return -2
+	}
+	return semVer1.Compare(semVer2)
+}
+
+// FilterNodeJSReleases keeps the releases matching the semver range (all if empty), optionally only LTS releases.
+// The order from nodejs.org (newest first) is kept.
+func FilterNodeJSReleases(releases NodeJSReleases, versionRange string, ltsOnly bool) NodeJSReleases {
+	var semVerRange SemVerRange
+	if versionRange != "" {
+		var err error
+		semVerRange, err = ParseSemVerRange(versionRange)
 		if err != nil {
-			return -2
+			log.Println("Failed with", err)
+			return NodeJSReleases{}
 		}
-		if v1Int > v2Int {
-			return 1
+	}
+	filtered := NodeJSReleases{}
+	for _, release := range releases {
+		if ltsOnly {
+			if isLTS, ok := release.Lts.(bool); release.Lts == nil || (ok && !isLTS) {
+				continue
+			}
 		}
-		if v1Int < v2Int {
-			return -1
+		if semVerRange != nil {
+			version, err := ParseSemVer(release.Version)
+			if err != nil || !semVerRange.Contains(version) {
+				continue
+			}
 		}
+		filtered = append(filtered, release)
 	}
-	return 0
+	return filtered
 }
 
 func GetLatestNodeJSRelease() (NodeJSReleases, error) {
@@ -7,7 +7,6 @@ import (
 	"log"
 	"os"
 	"os/exec"
-	"os/user"
 	"regexp"
 	"strconv"
 	"strings"
@@ -55,13 +54,17 @@ func RunNPM(packageJSON PackageJSON, path string, script string, args []string,
 			// from the config and NoDefaultValues only removes the default values from the current run
 			if flagList.NoDefaultValues == nil || *flagList.NoDefaultValues == false {
 				if len(envs[script]) > 0 {
-					envParts, _ := shlex.Split(envs[script])
+					scriptEnv, err := NewTemplateContext(flagList.Vars).Render(envs[script])
+					if err != nil {
+						return 1, err
+					}
+					envParts, _ := shlex.Split(scriptEnv)
 					for _, part := range envParts {
 						cmdEnv = append(cmdEnv, part)
 					}
 					if *flagList.BeVerbose {
 						fmt.Println("============================================================")
-						fmt.Println("Adding environment:", envs[script])
+						fmt.Println("Adding environment:", scriptEnv)
 						if flagList.UsedPath != "" {
 							fmt.Println("Using path:", flagList.UsedPath)
 						}
@@ -78,24 +81,20 @@ func RunNPM(packageJSON PackageJSON, path string, script string, args []string,
 				}
 			}
 
-			// Add node_modules/.bin to path if it exists
-			node_bin_modules := path + "/node_modules/.bin"
-			if IsDir(node_bin_modules) {
-				cmdEnv = append(cmdEnv, "PATH="+node_bin_modules+":"+os.Getenv("PATH"))
-			}
-
-			// Add npm root -g to path if it exists (for global npm packages)
-			npmRootGCmd := exec.Command("npm", "root -g")
-			npmRootGCmdPathBytes, _ := npmRootGCmd.Output()
-			npmRootGCmdPath := strings.Trim(string(npmRootGCmdPathBytes), " \n")
-			if len(npmRootGCmdPath) > 0 && IsDir(npmRootGCmdPath) {
-				cmdEnv = append(cmdEnv, "PATH="+npmRootGCmdPath+":"+os.Getenv("PATH"))
+			// Add node_modules/.bin from the path up to the workspace root and the global npm bin to the path
+			scriptPath := GetScriptPath(path)
+			cmdEnv = SetEnvValue(cmdEnv, "PATH", scriptPath)
+			if flagList.BeVerbose != nil && *flagList.BeVerbose {
+				fmt.Println("============================================================")
+				fmt.Println("Using PATH:")
+				for _, pathPart := range strings.Split(scriptPath, string(os.PathListSeparator)) {
+					fmt.Println("  " + pathPart)
+				}
+				fmt.Println("============================================================")
 			}
 
 			if *flagList.XAuthToken != "" {
-				usr, _ := user.Current()
-				dir := usr.HomeDir
-				config, err := ReadConfig(dir + "/.nrun.json")
+				config, err := GetEffectiveConfig("")
 				if err == nil {
 					if config.XAuthTokens[*flagList.XAuthToken] != "" {
 						cmdEnv = append(cmdEnv, "X_AUTH_TOKEN="+config.XAuthTokens[*flagList.XAuthToken])
@@ -109,7 +108,11 @@ func RunNPM(packageJSON PackageJSON, path string, script string, args []string,
 			scriptNice := strings.Replace(script, ":", "_", -1)
 			// Split this before we add it?
 			if len(envs[scriptNice]) > 0 {
-				cmdEnv = append(cmdEnv, envs[scriptNice])
+				scriptEnv, err := NewTemplateContext(flagList.Vars).Render(envs[scriptNice])
+				if err != nil {
+					return 1, err
+				}
+				cmdEnv = append(cmdEnv, scriptEnv)
 			}
 
 			// Manage overrides for env
@@ -195,21 +198,21 @@ func RunNPM(packageJSON PackageJSON, path string, script string, args []string,
 		} else {
 			if InternalCommands(packageJSON, script, args, envs, Version) == true {
 				// Do nothing
-			} else if PassthruNpm(packageJSON, script, args, envs, Version) == false {
+			} else if PassthruNpm(packageJSON, path, script, args, envs, Version) == false {
 				log.Println("Script", script, "does not exist")
 			}
 		}
 	} else {
 		if InternalCommands(packageJSON, script, args, envs, Version) == true {
 			// Do nothing
-		} else if PassthruNpm(packageJSON, script, args, envs, Version) == false {
+		} else if PassthruNpm(packageJSON, path, script, args, envs, Version) == false {
 			log.Println("No scripts defined in package.json")
 		}
 	}
 	return 0, nil
 }
 
-func PassthruNpm(packageJSON PackageJSON, script string, args []string, envs map[string]string, Version string) bool {
+func PassthruNpm(packageJSON PackageJSON, path string, script string, args []string, envs map[string]string, Version string) bool {
 	// Script names that are valid commands in npm
 	validScripts := []string{
 		"access",
@@ -282,16 +285,17 @@ func PassthruNpm(packageJSON PackageJSON, script string, args []string, envs map
 	if len(script) == 0 || Contains(validScripts, script) {
 		scriptArgs := []string{script}
 		args = append(scriptArgs, args...)
-		cmd := exec.Command("npm", args...)
+		packageManager := GetPackageManager(path)
+		cmd := exec.Command(packageManager, args...)
 		cmd.Stdout = os.Stdout
 		cmd.Stdin = os.Stdin
 		cmd.Stderr = os.Stderr
 		if script != "version" {
 			fmt.Println("========================================")
-			fmt.Println("Running \x1b[34mnpm", strings.Join(args[:], " "), "\x1b[0m")
+			fmt.Println("Running \x1b[34m"+packageManager, strings.Join(args[:], " "), "\x1b[0m")
 			fmt.Println("========================================")
 		} else {
-			fmt.Printf("nrun: {\n  nrun: '%s'\n},\nnpm: ", Version)
+			fmt.Printf("nrun: {\n  nrun: '%s'\n},\n%s: ", Version, packageManager)
 		}
 		runErr := cmd.Run()
 		if runErr != nil {
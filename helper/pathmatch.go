@@ -0,0 +1,198 @@
+package helper
+
+import (
+	"os/user"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// How specific a key in "path", "env", "pipes" or "package.json" is. Values from more specific keys win.
+const (
+	PathMatchNone = iota
+	PathMatchAny
+	PathMatchGlob
+	PathMatchTag
+	PathMatchProject
+	PathMatchExact
+)
+
+// ExpandHome replaces a leading ~ with the home directory.
+func ExpandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		usr, err := user.Current()
+		if err == nil {
+			return usr.HomeDir + path[1:]
+		}
+	}
+	return path
+}
+
+var globRegexpCache = make(map[string]*regexp.Regexp)
+
+// globToRegexp converts a glob to a regular expression. * and ? don't match /, ** matches any number of directories.
+func globToRegexp(pattern string) *regexp.Regexp {
+	if re, ok := globRegexpCache[pattern]; ok {
+		return re
+	}
+	var builder strings.Builder
+	builder.WriteString("^")
+	for index := 0; index < len(pattern); index++ {
+		switch char := pattern[index]; char {
+		case '*':
+			if strings.HasPrefix(pattern[index:], "**/") {
+				builder.WriteString("(.*/)?")
+				index += 2
+			} else if strings.HasPrefix(pattern[index:], "**") {
+				builder.WriteString(".*")
+				index++
+			} else {
+				builder.WriteString("[^/]*")
+			}
+		case '?':
+			builder.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[index:], ']')
+			if end < 0 {
+				builder.WriteString(`\[`)
+				continue
+			}
+			class := pattern[index+1 : index+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			builder.WriteString("[" + class + "]")
+			index += end
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	builder.WriteString("$")
+	re, err := regexp.Compile(builder.String())
+	if err != nil {
+		re = regexp.MustCompile("^" + regexp.QuoteMeta(pattern) + "$")
+	}
+	globRegexpCache[pattern] = re
+	return re
+}
+
+func IsGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// MatchGlob reports if the name matches the glob, see globToRegexp.
+func MatchGlob(pattern string, name string) bool {
+	return globToRegexp(pattern).MatchString(name)
+}
+
+func cleanConfigPath(path string) string {
+	path = ExpandHome(path)
+	if len(path) > 1 {
+		path = strings.TrimRight(path, "/")
+	}
+	return path
+}
+
+// isPathKeyPattern reports if a part of a key in a path keyed section is *, a glob, @project or tag:name instead
+// of a path. Code that reads the keys uses it so every kind of key is handled the same way.
+func isPathKeyPattern(part string) bool {
+	return part == "*" || strings.HasPrefix(part, "@") || strings.HasPrefix(part, "tag:") || IsGlob(part)
+}
+
+// hasProjectTag reports if a project in the path has the tag.
+func hasProjectTag(tag string, path string, projects map[string]string, details map[string]ProjectStruct) bool {
+	for name, projectPath := range projects {
+		if len(projectPath) == 0 || cleanConfigPath(projectPath) != path {
+			continue
+		}
+		for _, projectTag := range details[name].Tags {
+			if projectTag == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchPathKeyPart returns how specific a single key (no commas) matches the path.
+func matchPathKeyPart(part string, path string, projects map[string]string, details map[string]ProjectStruct) int {
+	part = strings.TrimSpace(part)
+	switch {
+	case part == "*":
+		return PathMatchAny
+	case len(part) > 1 && part[0] == '@':
+		if projectPath, ok := projects[part[1:]]; ok && len(projectPath) > 0 && cleanConfigPath(projectPath) == path {
+			return PathMatchProject
+		}
+	case strings.HasPrefix(part, "tag:"):
+		if hasProjectTag(strings.TrimSpace(part[4:]), path, projects, details) {
+			return PathMatchTag
+		}
+	case IsGlob(part):
+		if MatchGlob(cleanConfigPath(part), path) {
+			return PathMatchGlob
+		}
+	case !isPathKeyPattern(part) && cleanConfigPath(part) == path:
+		return PathMatchExact
+	}
+	return PathMatchNone
+}
+
+// MatchPathKey returns how specific a key in a path keyed section matches the path. The key can be a
+// comma separated list, the best match in the list is used.
+func MatchPathKey(key string, path string, projects map[string]string, details map[string]ProjectStruct) int {
+	path = cleanConfigPath(path)
+	best := PathMatchNone
+	for _, part := range strings.Split(key, ",") {
+		if match := matchPathKeyPart(part, path, projects, details); match > best {
+			best = match
+		}
+	}
+	return best
+}
+
+// globLiteralLength is used to order globs that match the same path, the glob with most fixed characters wins.
+func globLiteralLength(key string) int {
+	length := 0
+	for _, part := range strings.Split(key, ",") {
+		part = strings.TrimSpace(part)
+		if IsGlob(part) {
+			literal := len(part) - strings.Count(part, "*") - strings.Count(part, "?")
+			if literal > length {
+				length = literal
+			}
+		}
+	}
+	return length
+}
+
+// MatchingPathKeys returns the keys that match the path, least specific first, so values from later keys
+// should replace values from earlier keys. The order is exact > @project > tag:name > glob > *, globs with more
+// fixed characters are more specific, and keys that are equally specific are ordered by name.
+func MatchingPathKeys(keys []string, path string, projects map[string]string, details map[string]ProjectStruct) []string {
+	type match struct {
+		key         string
+		specificity int
+		literal     int
+	}
+	matches := []match{}
+	for _, key := range keys {
+		if specificity := MatchPathKey(key, path, projects, details); specificity != PathMatchNone {
+			matches = append(matches, match{key, specificity, globLiteralLength(key)})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].specificity != matches[j].specificity {
+			return matches[i].specificity < matches[j].specificity
+		}
+		if matches[i].literal != matches[j].literal {
+			return matches[i].literal < matches[j].literal
+		}
+		return matches[i].key < matches[j].key
+	})
+	matchingKeys := make([]string, 0, len(matches))
+	for _, match := range matches {
+		matchingKeys = append(matchingKeys, match.key)
+	}
+	return matchingKeys
+}
@@ -0,0 +1,71 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"/dev/services/*", "/dev/services/billing", true},
+		{"/dev/services/*", "/dev/services/billing/api", false},
+		{"/dev/**", "/dev/services/billing/api", true},
+		{"src/**/*.graphql", "src/schema.graphql", true},
+		{"src/**/*.graphql", "src/a/b/schema.graphql", true},
+		{"src/**/*.graphql", "src/a/schema.gql", false},
+		{"/dev/app-?", "/dev/app-1", true},
+		{"/dev/app-[ab]", "/dev/app-c", false},
+		{"/dev/app-[!ab]", "/dev/app-c", true},
+	}
+	for _, test := range tests {
+		if result := MatchGlob(test.pattern, test.name); result != test.expected {
+			t.Errorf("MatchGlob(%q, %q) = %v, expected %v", test.pattern, test.name, result, test.expected)
+		}
+	}
+}
+
+func TestMatchingPathKeys(t *testing.T) {
+	projects := map[string]string{"billing": "/dev/services/billing"}
+	keys := []string{"/dev/services/billing/", "*", "/dev/**", "@billing", "/dev/services/*", "/other", "@auth, /dev/services/b*"}
+	expected := []string{"*", "/dev/**", "/dev/services/*", "@auth, /dev/services/b*", "@billing", "/dev/services/billing/"}
+	if result := MatchingPathKeys(keys, "/dev/services/billing", projects, nil); !reflect.DeepEqual(result, expected) {
+		t.Errorf("MatchingPathKeys() = %q, expected %q", result, expected)
+	}
+
+	// A tag is more specific than a glob and less specific than the project
+	projects["web"] = "/dev/web"
+	details := map[string]ProjectStruct{"billing": {Tags: []string{"backend", "node"}}, "web": {Tags: []string{"frontend"}}}
+	keys = []string{"@billing", "tag:frontend", "tag:node", "/dev/services/*", "*", "tag: backend"}
+	expected = []string{"*", "/dev/services/*", "tag: backend", "tag:node", "@billing"}
+	if result := MatchingPathKeys(keys, "/dev/services/billing", projects, details); !reflect.DeepEqual(result, expected) {
+		t.Errorf("MatchingPathKeys() = %q, expected %q", result, expected)
+	}
+	expected = []string{"*", "tag:frontend"}
+	if result := MatchingPathKeys(keys, "/dev/web/", projects, details); !reflect.DeepEqual(result, expected) {
+		t.Errorf("MatchingPathKeys() = %q, expected %q", result, expected)
+	}
+	if result := MatchPathKey("tag:backend", "/dev/other", projects, details); result != PathMatchNone {
+		t.Errorf("MatchPathKey() = %d, expected no match outside of a project", result)
+	}
+}
+
+func TestIsPathKeyPattern(t *testing.T) {
+	tests := map[string]bool{
+		"*":                 true,
+		"@api":              true,
+		"tag:frontend":      true,
+		"/dev/services/*":   true,
+		"~/dev/app-?":       true,
+		"/dev/services/api": false,
+		"~/dev/api":         false,
+	}
+	for part, expected := range tests {
+		if result := isPathKeyPattern(part); result != expected {
+			t.Errorf("isPathKeyPattern(%q) = %v, expected %v", part, result, expected)
+		}
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"log"
 	"os"
 	"os/exec"
-	"os/user"
 )
 
 func ExecutePersonalFlags(flagList *FlagList) bool {
@@ -15,9 +14,11 @@ func ExecutePersonalFlags(flagList *FlagList) bool {
 		fmt.Println("###############################################")
 	}
 
-	usr, _ := user.Current()
-	homeDir := usr.HomeDir
-	config, _ := ReadConfig(homeDir + "/.nrun.json")
+	config, err := GetEffectiveConfig("")
+	if err != nil {
+		log.Println("Failed with", err)
+		return false
+	}
 	flagExecuted := false
 	for i, i2 := range flagList.PersonalFlags {
 		if i2 != nil && *i2 {
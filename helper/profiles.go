@@ -0,0 +1,180 @@
+package helper
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// The profile selected with --profile or NRUN_PROFILE, it's applied on top of the merged config files
+var activeProfile string
+
+// SelectProfile activates the profile given with --profile (or NRUN_PROFILE). Protected profiles have to be
+// confirmed, either by typing the name of the profile or with --yes.
+func SelectProfile(flagList *FlagList) error {
+	name := ""
+	if flagList.Profile != nil {
+		name = *flagList.Profile
+	}
+	if len(name) == 0 {
+		name = os.Getenv("NRUN_PROFILE")
+	}
+	activeProfile = ""
+	effectiveConfigCache = make(map[string]map[string]interface{}, 10)
+	if len(name) == 0 {
+		return nil
+	}
+	config, err := GetEffectiveConfig("")
+	if err != nil {
+		return err
+	}
+	profile, ok := config.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(config.Profiles))
+		for profileName := range config.Profiles {
+			names = append(names, profileName)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return errors.New("The profile \"" + name + "\" is not defined, there are no profiles in the config")
+		}
+		return errors.New("The profile \"" + name + "\" is not defined, available profiles are " + strings.Join(names, ", "))
+	}
+	if profile.Protected && (flagList.AssumeYes == nil || !*flagList.AssumeYes) {
+		if !IsTerminal(os.Stdin) {
+			return errors.New("The profile \"" + name + "\" is protected, use --yes to use it without a terminal")
+		}
+		answer := AskQuestion("The profile \"" + name + "\" is protected. Type the name of the profile to continue: ")
+		if answer != name {
+			return errors.New("Aborted")
+		}
+	}
+	activeProfile = name
+	effectiveConfigCache = make(map[string]map[string]interface{}, 10)
+	if flagList.BeVerbose != nil && *flagList.BeVerbose {
+		fmt.Println("Using profile", name)
+		if len(profile.Description) > 0 {
+			fmt.Println("  " + profile.Description)
+		}
+	}
+	return nil
+}
+
+// IsTerminal reports if the file is a terminal. /dev/null is a character device too, so it's checked separately.
+func IsTerminal(file *os.File) bool {
+	stat, err := file.Stat()
+	if err != nil || stat.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	devNull, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(stat, devNull)
+}
+
+// AskQuestion prints the question and returns the answer without the trailing newline.
+func AskQuestion(question string) string {
+	fmt.Print(question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimRight(answer, "\r\n")
+}
+
+// applyConfigProfile layers the active profile over the merged config. Vars and xauthtokens replace the
+// values with the same name and base URLs are put in front of webget template URLs that start with a /.
+// The env of the profile is added in GetDefaultValues.
+func applyConfigProfile(document map[string]interface{}, sources map[string]string, name string) {
+	profiles, _ := document["profiles"].(map[string]interface{})
+	profile, ok := profiles[name].(map[string]interface{})
+	if !ok {
+		return
+	}
+	source := "profile " + name
+	for _, section := range []string{"vars", "xauthtokens"} {
+		if values, ok := profile[section].(map[string]interface{}); ok {
+			mergeConfigDocuments(document, map[string]interface{}{section: values}, "", sources, nil, source)
+		}
+	}
+	if baseURLs, ok := profile["baseurls"].(map[string]interface{}); ok {
+		templates, _ := document["webget"].(map[string]interface{})
+		for templateName, template := range templates {
+			template, ok := template.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			baseURL, ok := baseURLs[templateName].(string)
+			if !ok {
+				baseURL, _ = baseURLs["*"].(string)
+			}
+			if url, ok := template["url"].(string); ok && len(baseURL) > 0 && strings.HasPrefix(url, "/") {
+				template["url"] = strings.TrimRight(baseURL, "/") + url
+				sources[appendJSONPath(appendJSONPath(appendJSONPath("", "webget"), templateName), "url")] = source
+			}
+		}
+	}
+}
+
+// splitEnvEntries splits an env string like `A=1 B="x y" C={{sh:git rev-parse HEAD}}` into its entries. Quotes and
+// placeholders are kept as they are, so the entries can be joined again before the string is rendered.
+func splitEnvEntries(env string) []string {
+	entries := []string{}
+	var entry strings.Builder
+	var quote byte
+	depth := 0
+	for index := 0; index < len(env); index++ {
+		char := env[index]
+		switch {
+		case quote != 0:
+			if char == '\\' && quote == '"' && index+1 < len(env) {
+				entry.WriteByte(char)
+				index++
+				char = env[index]
+			} else if char == quote {
+				quote = 0
+			}
+		case strings.HasPrefix(env[index:], "{{"):
+			depth++
+			entry.WriteByte(char)
+			index++
+			char = env[index]
+		case strings.HasPrefix(env[index:], "}}") && depth > 0:
+			depth--
+			entry.WriteByte(char)
+			index++
+			char = env[index]
+		case depth > 0:
+		case char == '"' || char == '\'':
+			quote = char
+		case char == ' ' || char == '\t' || char == '\n':
+			if entry.Len() > 0 {
+				entries = append(entries, entry.String())
+				entry.Reset()
+			}
+			continue
+		}
+		entry.WriteByte(char)
+	}
+	if entry.Len() > 0 {
+		entries = append(entries, entry.String())
+	}
+	return entries
+}
+
+// mergeEnv returns the env with the variables from override replacing the ones with the same name, so every
+// variable is only given once.
+func mergeEnv(env string, override string) string {
+	overrides := splitEnvEntries(override)
+	names := map[string]bool{}
+	for _, entry := range overrides {
+		if name, _, found := strings.Cut(entry, "="); found {
+			names[name] = true
+		}
+	}
+	merged := []string{}
+	for _, entry := range splitEnvEntries(env) {
+		if name, _, found := strings.Cut(entry, "="); !found || !names[name] {
+			merged = append(merged, entry)
+		}
+	}
+	return strings.Join(append(merged, overrides...), " ")
+}
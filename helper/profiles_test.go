@@ -0,0 +1,31 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitEnvEntries(t *testing.T) {
+	env := ` A=1  B="x y" C='it''s' D={{sh:git rev-parse --short HEAD}} E="a \" b" F `
+	expected := []string{"A=1", `B="x y"`, `C='it''s'`, "D={{sh:git rev-parse --short HEAD}}", `E="a \" b"`, "F"}
+	if result := splitEnvEntries(env); !reflect.DeepEqual(result, expected) {
+		t.Errorf("splitEnvEntries() = %q, expected %q", result, expected)
+	}
+}
+
+func TestProfileEnv(t *testing.T) {
+	useTestConfig(t, `{
+		"env": {"*": {"start": "NODE_ENV=development PORT=3000 API=\"http://localhost {{port|3000}}\""}},
+		"profiles": {"staging": {"env": {"*": {"start": "PORT=4000 API={{sh:echo staging api}}", "test": "CI=1"}}}}
+	}`)
+	defer func(profile string) { activeProfile = profile }(activeProfile)
+	activeProfile = "staging"
+
+	_, envs, _, _, _, _, _ := GetDefaultValues("/dev/nrun-test")
+	if expected := "NODE_ENV=development PORT=4000 API={{sh:echo staging api}}"; envs["start"] != expected {
+		t.Errorf("start = %q, expected %q", envs["start"], expected)
+	}
+	if expected := "CI=1"; envs["test"] != expected {
+		t.Errorf("test = %q, expected %q", envs["test"], expected)
+	}
+}
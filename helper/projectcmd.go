@@ -0,0 +1,368 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// findProjectConfigFile returns the global config file the project is defined in, or "" if it isn't defined.
+func findProjectConfigFile(name string) string {
+	files := GetGlobalConfigFiles()
+	for index := len(files) - 1; index >= 0; index-- {
+		if config, err := ReadConfig(files[index]); err == nil {
+			if _, ok := config.Projects[name]; ok {
+				return files[index]
+			}
+		}
+	}
+	return ""
+}
+
+// mapPathKeyParts calls mapPart for every part of a (comma separated) path key and keeps the spacing around the parts.
+func mapPathKeyParts(key string, mapPart func(string) string) string {
+	parts := strings.Split(key, ",")
+	for index, part := range parts {
+		trimmed := strings.TrimSpace(part)
+		if len(trimmed) == 0 {
+			continue
+		}
+		start := strings.Index(part, trimmed)
+		parts[index] = part[:start] + mapPart(trimmed) + part[start+len(trimmed):]
+	}
+	return strings.Join(parts, ",")
+}
+
+// mapConfigPathKeys renames the keys in the path keyed sections (and in the env of every profile) and
+// returns a description of every key that was renamed. Keys that would replace an existing key are left
+// as they are and reported in conflicts.
+func mapConfigPathKeys(config *Config, mapPart func(string) string) (renamed []string, conflicts []string) {
+	mapKeys := func(section string, keys []string, exists func(string) bool, move func(string, string)) {
+		sort.Strings(keys)
+		for _, key := range keys {
+			newKey := mapPathKeyParts(key, mapPart)
+			if newKey == key {
+				continue
+			}
+			if exists(newKey) {
+				conflicts = append(conflicts, fmt.Sprintf("%s: %q can't be renamed to %q since it already exists", section, key, newKey))
+				continue
+			}
+			move(key, newKey)
+			renamed = append(renamed, fmt.Sprintf("%s: %q -> %q", section, key, newKey))
+		}
+	}
+	mapStringSection := func(section string, values map[string]map[string]string) {
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		mapKeys(section, keys, func(key string) bool {
+			_, ok := values[key]
+			return ok
+		}, func(key string, newKey string) {
+			values[newKey] = values[key]
+			delete(values, key)
+		})
+	}
+
+	mapStringSection("env", config.Env)
+	mapStringSection("path", config.Path)
+	keys := make([]string, 0, len(config.Pipes))
+	for key := range config.Pipes {
+		keys = append(keys, key)
+	}
+	mapKeys("pipes", keys, func(key string) bool {
+		_, ok := config.Pipes[key]
+		return ok
+	}, func(key string, newKey string) {
+		config.Pipes[newKey] = config.Pipes[key]
+		delete(config.Pipes, key)
+	})
+	keys = make([]string, 0, len(config.PackageJSONOverride))
+	for key := range config.PackageJSONOverride {
+		keys = append(keys, key)
+	}
+	mapKeys("package.json", keys, func(key string) bool {
+		_, ok := config.PackageJSONOverride[key]
+		return ok
+	}, func(key string, newKey string) {
+		config.PackageJSONOverride[newKey] = config.PackageJSONOverride[key]
+		delete(config.PackageJSONOverride, key)
+	})
+	for name, profile := range config.Profiles {
+		mapStringSection("profiles."+name+".env", profile.Env)
+	}
+	return renamed, conflicts
+}
+
+// updateGlobalConfigFiles runs update on every global config file that exists.
+func updateGlobalConfigFiles(update func(file string, config *Config) error) error {
+	for _, file := range GetGlobalConfigFiles() {
+		if !FileExists(file) {
+			continue
+		}
+		if err := UpdateConfig(file, func(config *Config) error {
+			return update(file, config)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printConfigKeyChanges(file string, renamed []string, conflicts []string) {
+	for _, change := range renamed {
+		fmt.Println(file+":", change)
+	}
+	for _, conflict := range conflicts {
+		fmt.Println(file+": warning:", conflict)
+	}
+}
+
+// RenameProject gives a project a new name and updates the @name keys in the global config files.
+func RenameProject(oldName string, newName string) error {
+	file := findProjectConfigFile(oldName)
+	if len(file) == 0 {
+		return errors.New("Project \"" + oldName + "\" doesn't exists")
+	}
+	if other := findProjectConfigFile(newName); len(other) > 0 {
+		return errors.New("Project \"" + newName + "\" already exists in " + other)
+	}
+	err := updateGlobalConfigFiles(func(configFile string, config *Config) error {
+		changed := false
+		if configFile == file {
+			config.Projects[newName] = config.Projects[oldName]
+			delete(config.Projects, oldName)
+			if details, ok := config.ProjectDetails[oldName]; ok {
+				config.ProjectDetails[newName] = details
+				delete(config.ProjectDetails, oldName)
+			}
+			changed = true
+		}
+		renamed, conflicts := mapConfigPathKeys(config, func(part string) string {
+			if part == "@"+oldName {
+				return "@" + newName
+			}
+			return part
+		})
+		printConfigKeyChanges(configFile, renamed, conflicts)
+		if !changed && len(renamed) == 0 {
+			return ErrConfigUnchanged
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("Project", "\""+oldName+"\"", "renamed to", "\""+newName+"\"")
+	fmt.Println("Local config files that use @" + oldName + " have to be changed by hand")
+	return nil
+}
+
+// MoveProject changes the path of a project. Keys in the global config files with the old path are changed to
+// the new path.
+func MoveProject(name string, newPath string) error {
+	file := findProjectConfigFile(name)
+	if len(file) == 0 {
+		return errors.New("Project \"" + name + "\" doesn't exists")
+	}
+	newPath, err := filepath.Abs(ExpandHome(newPath))
+	if err != nil {
+		return err
+	}
+	if !IsDir(newPath) {
+		return errors.New("The path \"" + newPath + "\" doesn't exists")
+	}
+	config, _ := ReadConfig(file)
+	oldPath := cleanConfigPath(config.Projects[name])
+	err = updateGlobalConfigFiles(func(configFile string, config *Config) error {
+		changed := false
+		if configFile == file {
+			config.Projects[name] = newPath
+			changed = true
+		}
+		renamed, conflicts := mapConfigPathKeys(config, func(part string) string {
+			if !isPathKeyPattern(part) && cleanConfigPath(part) == oldPath {
+				return newPath
+			}
+			return part
+		})
+		printConfigKeyChanges(configFile, renamed, conflicts)
+		if !changed && len(renamed) == 0 {
+			return ErrConfigUnchanged
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("Project", "\""+name+"\"", "moved from", "\""+oldPath+"\"", "to", "\""+newPath+"\"")
+	return nil
+}
+
+// PruneProjects removes the projects whose directory doesn't exist anymore.
+func PruneProjects(flagList *FlagList) error {
+	config, err := GetEffectiveConfig("")
+	if err != nil {
+		return err
+	}
+	missing := []string{}
+	for name, path := range config.Projects {
+		if !IsDir(cleanConfigPath(path)) {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) == 0 {
+		fmt.Println("All projects exist")
+		return nil
+	}
+	for _, name := range missing {
+		fmt.Printf("%s : %s doesn't exist\n", name, config.Projects[name])
+	}
+	if flagList.AssumeYes == nil || !*flagList.AssumeYes {
+		if !IsTerminal(os.Stdin) {
+			fmt.Println("Nothing changed, use --yes to remove the projects without a terminal")
+			return nil
+		}
+		if answer := strings.ToLower(AskQuestion(fmt.Sprintf("Remove %d project(s)? [y/N] ", len(missing)))); answer != "y" && answer != "yes" {
+			fmt.Println("Nothing changed")
+			return nil
+		}
+	}
+	removed := []string{}
+	err = updateGlobalConfigFiles(func(file string, config *Config) error {
+		changed := false
+		for _, name := range missing {
+			if _, ok := config.Projects[name]; ok {
+				delete(config.Projects, name)
+				removed = append(removed, name)
+				changed = true
+			}
+		}
+		if !changed {
+			return ErrConfigUnchanged
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(removed)
+	fmt.Println("Removed", len(removed), "project(s):", strings.Join(removed, ", "))
+	return nil
+}
+
+// ProjectDoctor reports projects whose directory is missing and keys in the path keyed sections that refer to
+// unknown projects, tags that no project has or paths that no project points to.
+func ProjectDoctor() error {
+	config, err := GetEffectiveConfig("")
+	if err != nil {
+		return err
+	}
+	problems := []string{}
+	projectPaths := map[string]bool{}
+	names := make([]string, 0, len(config.Projects))
+	for name, path := range config.Projects {
+		names = append(names, name)
+		projectPaths[cleanConfigPath(path)] = true
+	}
+	sort.Strings(names)
+	tags := map[string]bool{}
+	for _, details := range config.ProjectDetails {
+		for _, tag := range details.Tags {
+			tags[tag] = true
+		}
+	}
+	for _, name := range names {
+		if !IsDir(cleanConfigPath(config.Projects[name])) {
+			problems = append(problems, fmt.Sprintf("projects.%s: %s doesn't exist (nrun --project prune removes it)", name, config.Projects[name]))
+		}
+	}
+
+	sections := map[string][]string{}
+	for key := range config.Env {
+		sections["env"] = append(sections["env"], key)
+	}
+	for key := range config.Path {
+		sections["path"] = append(sections["path"], key)
+	}
+	for key := range config.Pipes {
+		sections["pipes"] = append(sections["pipes"], key)
+	}
+	for key := range config.PackageJSONOverride {
+		sections["package.json"] = append(sections["package.json"], key)
+	}
+	for name, profile := range config.Profiles {
+		for key := range profile.Env {
+			sections["profiles."+name+".env"] = append(sections["profiles."+name+".env"], key)
+		}
+	}
+	sectionNames := make([]string, 0, len(sections))
+	for section := range sections {
+		sectionNames = append(sectionNames, section)
+	}
+	sort.Strings(sectionNames)
+	for _, section := range sectionNames {
+		keys := sections[section]
+		sort.Strings(keys)
+		for _, key := range keys {
+			for _, part := range strings.Split(key, ",") {
+				part = strings.TrimSpace(part)
+				switch {
+				case len(part) == 0:
+				case part[0] == '@':
+					if _, ok := config.Projects[part[1:]]; !ok {
+						problems = append(problems, fmt.Sprintf("%s[%q]: the project %q is not defined", section, key, part[1:]))
+					}
+				case strings.HasPrefix(part, "tag:"):
+					if tag := strings.TrimSpace(part[4:]); !tags[tag] {
+						problems = append(problems, fmt.Sprintf("%s[%q]: no project has the tag %q", section, key, tag))
+					}
+				case isPathKeyPattern(part):
+				case !IsDir(cleanConfigPath(part)):
+					problems = append(problems, fmt.Sprintf("%s[%q]: %s doesn't exist", section, key, part))
+				case !projectPaths[cleanConfigPath(part)]:
+					problems = append(problems, fmt.Sprintf("%s[%q]: no project points to %s", section, key, part))
+				}
+			}
+		}
+	}
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("Found %d problem(s)", len(problems))
+	}
+	fmt.Println("No problems found")
+	return nil
+}
+
+// ProjectCommand handles nrun --project rename|move|prune|doctor.
+func ProjectCommand(args []string, flagList *FlagList) error {
+	usage := errors.New("usage: nrun --project rename <project> <new name> | move <project> <path> | prune | doctor")
+	if len(args) == 0 {
+		return usage
+	}
+	switch args[0] {
+	case "rename":
+		if len(args) != 3 {
+			return usage
+		}
+		return RenameProject(args[1], args[2])
+	case "move":
+		if len(args) != 3 {
+			return usage
+		}
+		return MoveProject(args[1], args[2])
+	case "prune":
+		return PruneProjects(flagList)
+	case "doctor":
+		return ProjectDoctor()
+	}
+	return usage
+}
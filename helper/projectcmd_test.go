@@ -0,0 +1,38 @@
+package helper
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestProjectDoctor(t *testing.T) {
+	dir := t.TempDir()
+	config := `{"projects":{"web":{"path":"` + dir + `","tags":["frontend"]}},
+		"env":{"tag:frontend":{"start":"PORT=3000"},"@web, tag: frontend":{"test":"CI=1"}},
+		"path":{"tag:frontend":{"s":"start"}}%s}`
+	stdout := os.Stdout
+	defer func() { os.Stdout = stdout }()
+
+	tests := map[string]struct {
+		extra  string
+		output string
+	}{
+		"valid":   {"", "No problems found\n"},
+		"unknown": {`,"pipes":{"tag:backend":{"start":["jq ."]}}`, "pipes[\"tag:backend\"]: no project has the tag \"backend\"\n"},
+	}
+	for name, test := range tests {
+		useTestConfig(t, fmt.Sprintf(config, test.extra))
+		output, _ := os.CreateTemp(t.TempDir(), "stdout")
+		os.Stdout = output
+		err := ProjectDoctor()
+		os.Stdout = stdout
+		output.Seek(0, io.SeekStart)
+		data, _ := io.ReadAll(output)
+		output.Close()
+		if string(data) != test.output || (err != nil) != (name == "unknown") {
+			t.Errorf("%s: ProjectDoctor() = %v, %q, expected %q", name, err, data, test.output)
+		}
+	}
+}
@@ -1,30 +1,151 @@
 package helper
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"os"
-	"os/user"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 )
 
-func ListProjectsFromConfig() {
-	usr, _ := user.Current()
-	dir := usr.HomeDir
-	config, err := ReadConfig(dir + "/.nrun.json")
+// ProjectStatus is what -pl --status shows for a project.
+type ProjectStatus struct {
+	Name           string     `json:"name"`
+	Path           string     `json:"path"`
+	Description    string     `json:"description,omitempty"`
+	Tags           []string   `json:"tags,omitempty"`
+	Exists         *bool      `json:"exists,omitempty"`
+	HasPackageJSON *bool      `json:"packagejson,omitempty"`
+	Branch         string     `json:"branch,omitempty"`
+	Changed        int        `json:"changed,omitempty"`
+	Ahead          int        `json:"ahead,omitempty"`
+	Behind         int        `json:"behind,omitempty"`
+	LastRun        *RunRecord `json:"lastrun,omitempty"`
+}
+
+// getGitStatus fills in the branch and the number of changed files, and how far ahead or behind the upstream
+// branch is, if the project is a git repository.
+func (status *ProjectStatus) getGitStatus() {
+	output, err := exec.Command("git", "-C", status.Path, "status", "--porcelain=v2", "--branch").Output()
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		switch {
+		case strings.HasPrefix(line, "# branch.head "):
+			status.Branch = strings.TrimPrefix(line, "# branch.head ")
+		case strings.HasPrefix(line, "# branch.ab "):
+			_, _ = fmt.Sscanf(strings.TrimPrefix(line, "# branch.ab "), "+%d -%d", &status.Ahead, &status.Behind)
+		case len(line) > 0 && line[0] != '#':
+			status.Changed++
+		}
+	}
+}
+
+// GetProjectStatuses collects the status of every project concurrently, sorted by name.
+func GetProjectStatuses(projects map[string]string, details map[string]ProjectStruct, withStatus bool) []ProjectStatus {
+	statuses := make([]ProjectStatus, 0, len(projects))
+	for name, path := range projects {
+		statuses = append(statuses, ProjectStatus{Name: name, Path: path, Description: details[name].Description, Tags: details[name].Tags})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	if !withStatus {
+		return statuses
+	}
+	records := GetRunRecords()
+	// Running git status for every project at once can start hundreds of processes
+	var wg sync.WaitGroup
+	slots := make(chan bool, runtime.NumCPU())
+	for index := range statuses {
+		wg.Add(1)
+		slots <- true
+		go func(status *ProjectStatus) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			path := cleanConfigPath(status.Path)
+			exists := IsDir(path)
+			hasPackageJSON := exists && FileExists(path+"/package.json")
+			status.Exists = &exists
+			status.HasPackageJSON = &hasPackageJSON
+			if record, ok := records[path]; ok {
+				status.LastRun = &record
+			}
+			if exists {
+				status.getGitStatus()
+			}
+		}(&statuses[index])
+	}
+	wg.Wait()
+	return statuses
+}
+
+func (status ProjectStatus) describe() string {
+	if status.Exists != nil && !*status.Exists {
+		return "missing"
+	}
+	parts := []string{}
+	if status.HasPackageJSON != nil && !*status.HasPackageJSON {
+		parts = append(parts, "no package.json")
+	}
+	if len(status.Branch) > 0 {
+		git := status.Branch
+		if status.Changed > 0 {
+			git += fmt.Sprintf(" %d changed", status.Changed)
+		}
+		if status.Ahead > 0 {
+			git += fmt.Sprintf(" %d ahead", status.Ahead)
+		}
+		if status.Behind > 0 {
+			git += fmt.Sprintf(" %d behind", status.Behind)
+		}
+		parts = append(parts, git)
+	}
+	if status.LastRun != nil {
+		result := "ok"
+		if status.LastRun.ExitCode != 0 {
+			result = "failed with " + strconv.Itoa(status.LastRun.ExitCode)
+		}
+		parts = append(parts, "last run "+status.LastRun.Script+" "+status.LastRun.Time.Format("2006-01-02 15:04")+" "+result)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ListProjectsFromConfig lists the projects sorted by name. --status adds git and health information and
+// the last run, --json prints the list as JSON.
+func ListProjectsFromConfig(flagList *FlagList) {
+	config, err := GetEffectiveConfig("")
 	if err != nil {
 		return
 	}
+	withStatus := flagList.ProjectStatus != nil && *flagList.ProjectStatus
+	statuses := GetProjectStatuses(config.Projects, config.ProjectDetails, withStatus)
+	if flagList.JSONOutput != nil && *flagList.JSONOutput {
+		data, _ := json.MarshalIndent(statuses, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
 	maxLength := 0
-	count := 0
-	for k, _ := range config.Projects {
-		count++
-		if len(k) > maxLength {
-			maxLength = len(k)
+	maxPathLength := 0
+	for _, status := range statuses {
+		if len(status.Name) > maxLength {
+			maxLength = len(status.Name)
+		}
+		if len(status.Path) > maxPathLength {
+			maxPathLength = len(status.Path)
 		}
 	}
 
+	count := len(statuses)
 	if count > 0 {
 		if count == 1 {
 			fmt.Println("The following project is registered:")
@@ -34,78 +155,89 @@ func ListProjectsFromConfig() {
 	} else {
 		fmt.Println("No projects are registered.")
 	}
-	for k, v := range config.Projects {
-		fmt.Printf("%-*s : %s\n", maxLength, k, v)
+	for _, status := range statuses {
+		parts := []string{}
+		if withStatus && len(status.describe()) > 0 {
+			parts = append(parts, status.describe())
+		}
+		if len(status.Description) > 0 {
+			parts = append(parts, status.Description)
+		}
+		if len(status.Tags) > 0 {
+			parts = append(parts, "["+strings.Join(status.Tags, ", ")+"]")
+		}
+		if info := strings.Join(parts, ", "); len(info) > 0 {
+			fmt.Printf("%-*s : %-*s  %s\n", maxLength, status.Name, maxPathLength, status.Path, info)
+		} else {
+			fmt.Printf("%-*s : %s\n", maxLength, status.Name, status.Path)
+		}
 	}
 }
 
 func AddProjectToConfig(args []string) {
-	usr, _ := user.Current()
-	dir := usr.HomeDir
-	config, err := ReadConfig(dir + "/.nrun.json")
-	if err != nil {
-		log.Println("Failed with", err)
+	projPath := args[1]
+	if len(projPath) > 1 && projPath[0:2] == ".." {
+		cwd, _ := os.Getwd()
+		projPath = cwd + "/" + projPath
+	} else if projPath[0] == '.' {
+		cwd, _ := os.Getwd()
+		projPath = cwd + projPath[1:]
+	}
+	projPath, _ = filepath.Abs(projPath)
+	if _, err := os.Stat(projPath); errors.Is(err, os.ErrNotExist) {
+		log.Println("The path", "\""+projPath+"\"", "doesn't exists")
 		return
 	}
-	err = CopyFile(dir+"/.nrun.json", dir+"/.nrun.json.bak")
-	if err != nil {
-		log.Println("Failed with", err)
-	} else {
-		projPath := args[1]
-		if len(projPath) > 1 && projPath[0:2] == ".." {
-			cwd, _ := os.Getwd()
-			projPath = cwd + "/" + projPath
-		} else if projPath[0] == '.' {
-			cwd, _ := os.Getwd()
-			projPath = cwd + projPath[1:]
-		}
-		projPath, _ = filepath.Abs(projPath)
-		if _, err := os.Stat(projPath); errors.Is(err, os.ErrNotExist) {
-			log.Println("The path", "\""+projPath+"\"", "doesn't exists")
-			return
-		}
+	unchanged := false
+	err := UpdateConfig(GetGlobalConfigFile(), func(config *Config) error {
 		if _, ok := config.Projects[args[0]]; ok {
 			if config.Projects[args[0]] == projPath {
 				log.Println("Project", "\""+args[0]+"\"", "already exists with this path")
-				return
+				unchanged = true
+				return ErrConfigUnchanged
 			}
 			log.Println("Project", "\""+args[0]+"\"", "located at", "\""+config.Projects[args[0]]+"\"", "will be replaced with", "\""+projPath+"\"")
 		}
-		config.Projects[args[0]] = projPath
-		err := WriteConfig(dir+"/.nrun.json", config)
-		if err != nil {
-			log.Println("Failed with", err)
-		} else {
-			log.Println("Project", "\""+args[0]+"\"", "added")
+		if config.Projects == nil {
+			config.Projects = make(map[string]string)
 		}
+		config.Projects[args[0]] = projPath
+		return nil
+	})
+	if unchanged {
+		return
 	}
-}
-
-func RemoveProjectFromConfig(args []string) {
-	usr, _ := user.Current()
-	dir := usr.HomeDir
-	config, err := ReadConfig(dir + "/.nrun.json")
-	err = CopyFile(dir+"/.nrun.json", dir+"/.nrun.json.bak")
 	if err != nil {
 		log.Println("Failed with", err)
 	} else {
-		delete(config.Projects, args[0])
-		err := WriteConfig(dir+"/.nrun.json", config)
+		log.Println("Project", "\""+args[0]+"\"", "added")
+	}
+}
+
+func RemoveProjectFromConfig(args []string) {
+	for _, name := range args {
+		file := findProjectConfigFile(name)
+		if len(file) == 0 {
+			log.Println("Project", "\""+name+"\"", "doesn't exists")
+			continue
+		}
+		err := UpdateConfig(file, func(config *Config) error {
+			if _, ok := config.Projects[name]; !ok {
+				return ErrConfigUnchanged
+			}
+			delete(config.Projects, name)
+			return nil
+		})
 		if err != nil {
 			log.Println("Failed with", err)
+			return
 		}
-		log.Println("Project", "\""+args[0]+"\"", "removed")
-		if len(args) > 1 {
-			args = args[1:]
-			RemoveProjectFromConfig(args)
-		}
+		log.Println("Project", "\""+name+"\"", "removed")
 	}
 }
 
 func GetProjectPath(args []string) {
-	usr, _ := user.Current()
-	dir := usr.HomeDir
-	config, err := ReadConfig(dir + "/.nrun.json")
+	config, err := GetEffectiveConfig("")
 	if err != nil {
 		log.Println("Failed with", err)
 		return
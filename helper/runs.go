@@ -0,0 +1,58 @@
+package helper
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RunRecord is the last script nrun ran in a project.
+type RunRecord struct {
+	Script   string    `json:"script"`
+	Time     time.Time `json:"time"`
+	Duration float64   `json:"duration"`
+	ExitCode int       `json:"exitcode"`
+}
+
+func getRunRecordFile() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return cacheDir + "/nrun/runs.json"
+}
+
+// GetRunRecords returns the last run in every project, keyed by the path of the project.
+func GetRunRecords() map[string]RunRecord {
+	records := make(map[string]RunRecord)
+	if file := getRunRecordFile(); len(file) > 0 {
+		if data, err := os.ReadFile(file); err == nil {
+			_ = json.Unmarshal(data, &records)
+		}
+	}
+	return records
+}
+
+// RecordRun saves the result of a script run in a project. Failing to save it isn't worth bothering the user about.
+func RecordRun(path string, script string, started time.Time, exitCode int) {
+	file := getRunRecordFile()
+	if len(file) == 0 || os.MkdirAll(filepath.Dir(file), 0755) != nil {
+		return
+	}
+	unlock, err := LockConfigFile(file)
+	if err != nil {
+		return
+	}
+	defer unlock()
+	records := GetRunRecords()
+	records[cleanConfigPath(path)] = RunRecord{
+		Script:   script,
+		Time:     started,
+		Duration: time.Since(started).Seconds(),
+		ExitCode: exitCode,
+	}
+	if data, err := json.MarshalIndent(records, "", "  "); err == nil {
+		_ = writeFileAtomic(file, data)
+	}
+}
@@ -0,0 +1,120 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// parseCapture splits "NAME: command" or "NAME path: command", where path picks a field from JSON output in the
+// same form as --config get, e.g. version or dependencies.react. A path can contain colons, so it ends at the first
+// colon that is followed by a space and isn't in a quoted key like ["a:b"].
+func parseCapture(args string) (name string, path string, command string, err error) {
+	usage := errors.New("usage: @@capture NAME [json path]: command")
+	end := -1
+	quoted := false
+	for index := 0; index < len(args) && end < 0; index++ {
+		switch {
+		case quoted && args[index] == '\\':
+			index++
+		case quoted && args[index] == '"':
+			quoted = false
+		case quoted:
+		case strings.HasPrefix(args[index:], "[\""):
+			quoted = true
+			index++
+		case args[index] == ':':
+			// Without a path the command can follow the colon directly, like NAME:command
+			if index+1 == len(args) || args[index+1] == ' ' || args[index+1] == '\t' || identifierRegexp.MatchString(strings.TrimSpace(args[:index])) {
+				end = index
+			}
+		}
+	}
+	if end < 0 {
+		return "", "", "", usage
+	}
+	head := strings.TrimSpace(args[:end])
+	command = strings.TrimSpace(args[end+1:])
+	fields := strings.Fields(head)
+	if len(command) == 0 || len(fields) == 0 || !identifierRegexp.MatchString(fields[0]) {
+		return "", "", "", usage
+	}
+	name = fields[0]
+	path = strings.TrimSpace(strings.TrimPrefix(head, name))
+	if _, err := ParseConfigPath(path); err != nil {
+		return "", "", "", err
+	}
+	return name, path, command, nil
+}
+
+// extractJSONValue returns the value at the path in the JSON data. Text is returned as it is, anything else as JSON.
+func extractJSONValue(data string, path string) (string, error) {
+	var document interface{}
+	if err := json.Unmarshal([]byte(data), &document); err != nil {
+		return "", errors.New("the output isn't JSON: " + err.Error())
+	}
+	segments, err := ParseConfigPath(path)
+	if err != nil {
+		return "", err
+	}
+	value, ok := getConfigValue(document, segments)
+	if !ok {
+		return "", errors.New("\"" + path + "\" isn't in the output")
+	}
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	}
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
+
+// runCapture runs a command and stores its output without the surrounding whitespace as a var and an environment
+// variable for the rest of the script. The command fails the script like any other command.
+func (r *scriptRunner) runCapture(node *scriptNode) error {
+	args, err := r.templateContext.Render(node.args)
+	if err != nil {
+		return fmt.Errorf("Failed with %s", err)
+	}
+	name, path, command, err := parseCapture(args)
+	if err != nil {
+		return r.errorf(node, "%s", err)
+	}
+	if r.flagList.BeVerbose != nil && *r.flagList.BeVerbose {
+		fmt.Println("Capturing", "\""+command+"\"", "in", name)
+	}
+	shell, err := GetShell()
+	if err != nil {
+		return errors.New("Error: " + err.Error())
+	}
+	cmd := exec.Command(shell, "-c", command)
+	cmd.Dir = r.path
+	cmd.Env = r.commandEnv(r.path, command)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	err = r.interrupt.run(cmd)
+	setLastExit(err)
+	if err != nil {
+		return r.errorf(node, "\"%s\" failed: %w", command, err)
+	}
+	value := strings.TrimSpace(output.String())
+	if len(path) > 0 {
+		if value, err = extractJSONValue(value, path); err != nil {
+			return r.errorf(node, "%s", err)
+		}
+	}
+	r.vars[name] = escapeTemplate(value)
+	os.Setenv(name, value)
+	if r.flagList.BeVerbose != nil && *r.flagList.BeVerbose {
+		fmt.Println("Captured", name+"="+value)
+	}
+	return nil
+}
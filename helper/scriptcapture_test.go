@@ -0,0 +1,58 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCapture(t *testing.T) {
+	tests := []struct {
+		args    string
+		name    string
+		path    string
+		command string
+	}{
+		{"BRANCH: git rev-parse --abbrev-ref HEAD", "BRANCH", "", "git rev-parse --abbrev-ref HEAD"},
+		{"VERSION version: cat package.json", "VERSION", "version", "cat package.json"},
+		{" REACT  dependencies.react :  cat package.json ", "REACT", "dependencies.react", "cat package.json"},
+		{"TIME: date +%H:%M", "TIME", "", "date +%H:%M"},
+		{"TIME:date +%H:%M", "TIME", "", "date +%H:%M"},
+		{"PORT services.api:http.port: docker inspect api", "PORT", "services.api:http.port", "docker inspect api"},
+		{`URL ["host: port"].url: echo '{}'`, "URL", `["host: port"].url`, "echo '{}'"},
+		{`URL ["a\"b: c"]: echo`, "URL", `["a\"b: c"]`, "echo"},
+	}
+	for _, test := range tests {
+		name, path, command, err := parseCapture(test.args)
+		if err != nil || name != test.name || path != test.path || command != test.command {
+			t.Errorf("parseCapture(%q) = %q, %q, %q, %v, expected %q, %q, %q", test.args, name, path, command, err, test.name, test.path, test.command)
+		}
+	}
+	for _, args := range []string{"NAME", "NAME:", ": echo", "1NAME: echo", "name.json:a:b: echo", `NAME ["a: echo`} {
+		if _, _, _, err := parseCapture(args); err == nil {
+			t.Errorf("parseCapture(%q) should fail", args)
+		}
+	}
+}
+
+func TestExtractJSONValue(t *testing.T) {
+	data := `{"version":"1.2.0","a:b":{"c":"colon"},"items":[{"id":7,"tags":["a"]}],"dependencies":{"react":"^18.2.0"},"empty":null}`
+	tests := map[string]string{
+		"version":            "1.2.0",
+		"dependencies.react": "^18.2.0",
+		`["a:b"].c`:          "colon",
+		"items[0].id":        "7",
+		"items[0].tags":      `["a"]`,
+		"empty":              "",
+	}
+	for path, expected := range tests {
+		if value, err := extractJSONValue(data, path); err != nil || value != expected {
+			t.Errorf("extractJSONValue(%q) = %q, %v, expected %q", path, value, err, expected)
+		}
+	}
+	if _, err := extractJSONValue(data, "items[1].id"); err == nil || !strings.Contains(err.Error(), "isn't in the output") {
+		t.Errorf("expected a missing field error, got %v", err)
+	}
+	if _, err := extractJSONValue("v1.2.0", "version"); err == nil || !strings.Contains(err.Error(), "isn't JSON") {
+		t.Errorf("expected an error for output that isn't JSON, got %v", err)
+	}
+}
@@ -0,0 +1,303 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Conditions are used by @@if and @@elif in nrun scripts.
+//
+//	hasfile: a,b         one of the files exists (hasfiles: all of them, isfile: a file, isdir: a directory)
+//	env.NAME or $NAME    the environment variable, true if it's set to something else than "", "0" or "false"
+//	arg.0                the first argument given to the script, args is all of them
+//	a == b, a != b       compare two values, <, <=, > and >= compare numbers if both values are numbers
+//	a =~ re, a !~ re     match a value against a regular expression
+//	not x, !x, x and y, x or y, (x)
+//
+// Values can be quoted with " or ' and operators have to be separated by spaces.
+type conditionToken struct {
+	text   string
+	quoted bool
+}
+
+var conditionTests = map[string]bool{"hasfile": true, "hasfiles": true, "isfile": true, "isdir": true}
+
+var conditionOperators = map[string]bool{"==": true, "!=": true, "=~": true, "!~": true, "<": true, "<=": true, ">": true, ">=": true}
+
+func tokenizeCondition(condition string) ([]conditionToken, error) {
+	tokens := []conditionToken{}
+	current := strings.Builder{}
+	inWord := false
+	flush := func() {
+		if inWord {
+			tokens = append(tokens, conditionToken{text: current.String()})
+			current.Reset()
+			inWord = false
+		}
+	}
+	for index := 0; index < len(condition); index++ {
+		char := condition[index]
+		switch {
+		case char == ' ' || char == '\t':
+			flush()
+		case char == '(' || char == ')':
+			flush()
+			tokens = append(tokens, conditionToken{text: string(char)})
+		case (char == '"' || char == '\'') && !inWord:
+			value := strings.Builder{}
+			closed := false
+			for index++; index < len(condition); index++ {
+				if condition[index] == '\\' && char == '"' && index+1 < len(condition) {
+					index++
+					value.WriteByte(condition[index])
+					continue
+				}
+				if condition[index] == char {
+					closed = true
+					break
+				}
+				value.WriteByte(condition[index])
+			}
+			if !closed {
+				return nil, errors.New("missing closing quote in condition")
+			}
+			tokens = append(tokens, conditionToken{text: value.String(), quoted: true})
+		default:
+			current.WriteByte(char)
+			inWord = true
+			// "hasfile:" and "hasfile:.git" both start a test
+			if char == ':' && conditionTests[strings.TrimPrefix(current.String()[:current.Len()-1], "!")] {
+				flush()
+			}
+		}
+	}
+	flush()
+	return tokens, nil
+}
+
+type conditionParser struct {
+	tokens []conditionToken
+	pos    int
+	path   string
+	args   []string
+}
+
+// EvalCondition evaluates a condition of an nrun script, files are relative to path and arg.N refers to args.
+func EvalCondition(condition string, path string, args []string) (bool, error) {
+	tokens, err := tokenizeCondition(condition)
+	if err != nil {
+		return false, err
+	}
+	if len(tokens) == 0 {
+		return false, errors.New("empty condition")
+	}
+	parser := &conditionParser{tokens: tokens, path: path, args: args}
+	result, err := parser.parseOr()
+	if err != nil {
+		return false, err
+	}
+	if parser.pos < len(parser.tokens) {
+		return false, fmt.Errorf("unexpected %q in condition", parser.tokens[parser.pos].text)
+	}
+	return result, nil
+}
+
+func (p *conditionParser) peek() (conditionToken, bool) {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos], true
+	}
+	return conditionToken{}, false
+}
+
+// isKeyword checks if the next token is one of the words, quoted values are never keywords.
+func (p *conditionParser) isKeyword(words ...string) bool {
+	token, ok := p.peek()
+	if !ok || token.quoted {
+		return false
+	}
+	for _, word := range words {
+		if token.text == word {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *conditionParser) parseOr() (bool, error) {
+	result, err := p.parseAnd()
+	if err != nil {
+		return false, err
+	}
+	for p.isKeyword("or", "||") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return false, err
+		}
+		result = result || right
+	}
+	return result, nil
+}
+
+func (p *conditionParser) parseAnd() (bool, error) {
+	result, err := p.parseUnary()
+	if err != nil {
+		return false, err
+	}
+	for p.isKeyword("and", "&&") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return false, err
+		}
+		result = result && right
+	}
+	return result, nil
+}
+
+func (p *conditionParser) parseUnary() (bool, error) {
+	token, ok := p.peek()
+	if !ok {
+		return false, errors.New("unexpected end of condition")
+	}
+	if p.isKeyword("not", "!") {
+		p.pos++
+		result, err := p.parseUnary()
+		return !result, err
+	}
+	if p.isKeyword("(") {
+		p.pos++
+		result, err := p.parseOr()
+		if err != nil {
+			return false, err
+		}
+		if !p.isKeyword(")") {
+			return false, errors.New("missing ) in condition")
+		}
+		p.pos++
+		return result, nil
+	}
+	if !token.quoted && len(token.text) > 1 && token.text[0] == '!' && !conditionOperators[token.text] {
+		// !hasfile: x and !env.CI
+		p.tokens[p.pos].text = token.text[1:]
+		result, err := p.parseUnary()
+		return !result, err
+	}
+	if !token.quoted && strings.HasSuffix(token.text, ":") && conditionTests[strings.TrimSuffix(token.text, ":")] {
+		p.pos++
+		return p.parseTest(strings.TrimSuffix(token.text, ":"))
+	}
+	if p.isKeyword(")", "and", "or", "&&", "||") || (!token.quoted && conditionOperators[token.text]) {
+		return false, fmt.Errorf("unexpected %q in condition", token.text)
+	}
+	p.pos++
+	left := p.resolve(token)
+	operator, ok := p.peek()
+	if !ok || operator.quoted || !conditionOperators[operator.text] {
+		return isTrue(left), nil
+	}
+	p.pos++
+	right, ok := p.peek()
+	if !ok {
+		return false, errors.New("missing value after " + operator.text)
+	}
+	p.pos++
+	return compareValues(left, operator.text, p.resolve(right))
+}
+
+// parseTest reads the file names up to the next and, or or ) and checks them.
+func (p *conditionParser) parseTest(test string) (bool, error) {
+	words := []string{}
+	for p.pos < len(p.tokens) && !p.isKeyword("and", "or", "&&", "||", ")") {
+		words = append(words, p.tokens[p.pos].text)
+		p.pos++
+	}
+	files := []string{}
+	for _, file := range strings.Split(strings.Join(words, " "), ",") {
+		if file = strings.TrimSpace(file); len(file) > 0 {
+			if !strings.HasPrefix(file, "/") {
+				file = p.path + "/" + file
+			}
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return false, errors.New(test + " needs a file name")
+	}
+	found := 0
+	for _, file := range files {
+		switch {
+		case test == "isfile" && IsFile(file), test == "isdir" && IsDir(file), strings.HasPrefix(test, "hasfile") && FileExists(file):
+			found++
+		}
+	}
+	if test == "hasfiles" {
+		return found == len(files), nil
+	}
+	return found > 0, nil
+}
+
+func (p *conditionParser) resolve(token conditionToken) string {
+	if token.quoted {
+		return token.text
+	}
+	switch {
+	case strings.HasPrefix(token.text, "env."):
+		return os.Getenv(token.text[4:])
+	case strings.HasPrefix(token.text, "$") && len(token.text) > 1:
+		return os.Getenv(token.text[1:])
+	case token.text == "args":
+		return strings.Join(p.args, " ")
+	case strings.HasPrefix(token.text, "arg."):
+		if index, err := strconv.Atoi(token.text[4:]); err == nil {
+			if index >= 0 && index < len(p.args) {
+				return p.args[index]
+			}
+			return ""
+		}
+	}
+	return token.text
+}
+
+func isTrue(value string) bool {
+	return value != "" && value != "0" && strings.ToLower(value) != "false"
+}
+
+func compareValues(left string, operator string, right string) (bool, error) {
+	switch operator {
+	case "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	case "=~", "!~":
+		re, err := regexp.Compile(right)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(left) == (operator == "=~"), nil
+	}
+	comparison := strings.Compare(left, right)
+	leftNumber, leftErr := strconv.ParseFloat(left, 64)
+	rightNumber, rightErr := strconv.ParseFloat(right, 64)
+	if leftErr == nil && rightErr == nil {
+		comparison = 0
+		if leftNumber < rightNumber {
+			comparison = -1
+		} else if leftNumber > rightNumber {
+			comparison = 1
+		}
+	}
+	switch operator {
+	case "<":
+		return comparison < 0, nil
+	case "<=":
+		return comparison <= 0, nil
+	case ">":
+		return comparison > 0, nil
+	}
+	return comparison >= 0, nil
+}
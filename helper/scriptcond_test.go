@@ -0,0 +1,118 @@
+package helper
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEvalCondition(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(dir+"/package.json", []byte("{}"), 0644)
+	os.Mkdir(dir+"/src", 0755)
+	os.Setenv("NRUN_CONDITION_TEST", "production")
+	args := []string{"deploy", "3"}
+	tests := []struct {
+		condition string
+		expected  bool
+	}{
+		{"hasfile: package.json", true},
+		{"hasfile:.git", false},
+		{"hasfile: .git, package.json", true},
+		{"hasfiles: .git, package.json", false},
+		{"!hasfile: .git", true},
+		{"isdir: src and isfile: package.json", true},
+		{"isfile: src or isdir: nothing", false},
+		{"not (isdir: src and hasfile: .git)", true},
+		{"env.NRUN_CONDITION_TEST == production", true},
+		{"$NRUN_CONDITION_TEST != 'production'", false},
+		{"env.NRUN_CONDITION_TEST =~ ^prod", true},
+		{"env.NRUN_CONDITION_UNDEFINED", false},
+		{"!env.NRUN_CONDITION_UNDEFINED", true},
+		{"arg.0 == deploy and arg.1 >= 10", false},
+		{"arg.1 < 10", true},
+		{"arg.5 == ''", true},
+		{`args == "deploy 3"`, true},
+		{"false or true and false", false},
+	}
+	for _, test := range tests {
+		result, err := EvalCondition(test.condition, dir, args)
+		if err != nil {
+			t.Errorf("EvalCondition(%q) returned error %v", test.condition, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("EvalCondition(%q) = %v, expected %v", test.condition, result, test.expected)
+		}
+	}
+	for _, condition := range []string{"", "(isdir: src", "a ==", "a == b c", "'open", "and", "a =~ ("} {
+		if _, err := EvalCondition(condition, dir, args); err == nil {
+			t.Errorf("EvalCondition(%q) should return an error", condition)
+		}
+	}
+}
+
+func TestParseScript(t *testing.T) {
+	nodes, err := parseScript([]string{
+		"echo start",
+		"@@if hasfile: .git",
+		"@@if isdir: src",
+		"echo src",
+		"@@end",
+		"@@elif: isdir: .svn",
+		"echo svn",
+		"@@else",
+		"echo none",
+		"@@endif",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 || len(nodes[1].branches) != 3 {
+		t.Fatalf("unexpected blocks %+v", nodes)
+	}
+	if branch := nodes[1].branches[1]; branch.condition != "isdir: .svn" || branch.body[0].text != "echo svn" {
+		t.Errorf("unexpected @@elif %+v", branch)
+	}
+	if nodes[1].branches[2].condition != "" || len(nodes[1].branches[0].body[0].branches) != 1 {
+		t.Errorf("unexpected blocks %+v", nodes[1].branches)
+	}
+
+	nodes, err = parseScript([]string{"@@foreach item in a,b", "@@if {{item}} == a", "@@continue", "@@endif", "echo {{item}}", "@@endforeach"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0].directive != "foreach" || len(nodes[0].body) != 2 || nodes[0].body[0].branches[0].body[0].directive != "continue" {
+		t.Errorf("unexpected loop %+v", nodes)
+	}
+	nodes, err = parseScript([]string{"-npm run lint", "@@try", "npm test", "@@catch", "- @@call: report", "@@finally", "docker compose down", "@@endtry"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 || !nodes[0].ignoreErrors || nodes[0].text != "npm run lint" {
+		t.Errorf("unexpected ignored line %+v", nodes[0])
+	}
+	if try := nodes[1]; !try.hasCatch || len(try.body) != 1 || try.catch[0].directive != "call" || !try.catch[0].ignoreErrors || try.finally[0].text != "docker compose down" {
+		t.Errorf("unexpected @@try %+v", try)
+	}
+	for _, lines := range [][]string{
+		{"@@if hasfile: a", "echo a"},
+		{"@@else"},
+		{"@@if", "@@endif"},
+		{"@@if a", "@@else", "@@elif b", "@@endif"},
+		{"@@foreach item a,b", "@@end"},
+		{"@@foreach item in a,b", "@@if c", "@@endforeach", "@@endif"},
+		{"@@if c", "@@break", "@@endif"},
+		{"@@parallel", "@@if c", "@@endif", "@@end"},
+		{"@@parallel: 0", "@@end"},
+		{"@@if c", "@@endparallel"},
+		{"-@@foreach i in a", "@@end"},
+		{"@@try", "@@catch", "@@catch", "@@end"},
+		{"@@try", "@@finally", "@@catch", "@@end"},
+		{"@@try", "echo"},
+		{"@@finally"},
+	} {
+		if _, err := parseScript(lines); err == nil {
+			t.Errorf("parseScript(%q) should return an error", lines)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package helper
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var foreachRegexp = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s+in\s+(.+)$`)
+
+// foreachItem is a value of a @@foreach loop and the directory the iteration runs in.
+type foreachItem struct {
+	value string
+	dir   string
+}
+
+// errLoopBreak and errLoopContinue are returned by @@break and @@continue and handled by the loop
+var errLoopBreak = errors.New("@@break outside of a loop")
+var errLoopContinue = errors.New("@@continue outside of a loop")
+
+// expandForeachItems returns the values of a comma separated @@foreach list. A part is expanded to
+//
+//	@name          the project, the iteration runs in its directory
+//	@*             every project
+//	tag:frontend   every project with the tag
+//	src/**/*.ts    the files matching the glob, relative to path
+//	anything else  the value itself
+func expandForeachItems(list string, path string) ([]foreachItem, error) {
+	items := []foreachItem{}
+	var config *Config
+	projectItems := func(match func(name string) bool) {
+		names := make([]string, 0, len(config.Projects))
+		for name := range config.Projects {
+			if match(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			items = append(items, foreachItem{value: name, dir: cleanConfigPath(config.Projects[name])})
+		}
+	}
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+		if strings.HasPrefix(part, "@") || strings.HasPrefix(part, "tag:") {
+			if config == nil {
+				var err error
+				if config, err = GetEffectiveConfig(""); err != nil {
+					return nil, err
+				}
+			}
+		}
+		switch {
+		case part == "@*":
+			projectItems(func(name string) bool { return true })
+		case strings.HasPrefix(part, "@"):
+			if _, ok := config.Projects[part[1:]]; !ok {
+				return nil, errors.New("the project \"" + part[1:] + "\" is not defined")
+			}
+			projectItems(func(name string) bool { return name == part[1:] })
+		case strings.HasPrefix(part, "tag:"):
+			tag := strings.TrimSpace(part[4:])
+			projectItems(func(name string) bool {
+				for _, projectTag := range config.ProjectDetails[name].Tags {
+					if projectTag == tag {
+						return true
+					}
+				}
+				return false
+			})
+		case IsGlob(part):
+			files, err := globFiles(part, path)
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range files {
+				items = append(items, foreachItem{value: file, dir: path})
+			}
+		default:
+			items = append(items, foreachItem{value: part, dir: path})
+		}
+	}
+	return items, nil
+}
+
+// globFiles returns the files matching the glob, relative to path unless the glob is absolute. The walk starts
+// in the directory before the first part with a wildcard and skips .git and node_modules unless the glob
+// mentions them.
+func globFiles(pattern string, path string) ([]string, error) {
+	pattern = ExpandHome(pattern)
+	root := path
+	if filepath.IsAbs(pattern) {
+		root = "/"
+	}
+	parts := strings.Split(pattern, "/")
+	for index, part := range parts {
+		if IsGlob(part) {
+			root = filepath.Join(root, strings.Join(parts[:index], "/"))
+			break
+		}
+	}
+	files := []string{}
+	err := filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if file == root && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			if file != root && (entry.Name() == ".git" || entry.Name() == "node_modules") && !strings.Contains(pattern, entry.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := file
+		if !filepath.IsAbs(pattern) {
+			if name, err = filepath.Rel(path, file); err != nil {
+				return nil
+			}
+		}
+		if MatchGlob(pattern, filepath.ToSlash(name)) {
+			files = append(files, name)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// escapeTemplate makes sure a value that is used as a var isn't rendered as a template itself.
+func escapeTemplate(value string) string {
+	return strings.ReplaceAll(value, "{{", `\{{`)
+}
@@ -0,0 +1,30 @@
+package helper
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandForeachItems(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"src/a.graphql", "src/deep/b.graphql", "src/c.ts", "node_modules/x/d.graphql"} {
+		os.MkdirAll(filepath.Dir(dir+"/"+file), 0755)
+		os.WriteFile(dir+"/"+file, []byte{}, 0644)
+	}
+	items, err := expandForeachItems("src/**/*.graphql, one ,two", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []foreachItem{{"src/a.graphql", dir}, {"src/deep/b.graphql", dir}, {"one", dir}, {"two", dir}}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("expandForeachItems() = %v, expected %v", items, expected)
+	}
+	if items, _ := expandForeachItems("**/*.graphql", dir); len(items) != 2 {
+		t.Errorf("node_modules should be skipped, got %v", items)
+	}
+	if items, _ := expandForeachItems("missing/*.ts", dir); len(items) != 0 {
+		t.Errorf("expected no files, got %v", items)
+	}
+}
@@ -0,0 +1,117 @@
+package helper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// prefixWriter writes every line with a prefix. Lines from different commands are written under the same
+// mutex so they don't get mixed up.
+type prefixWriter struct {
+	prefix string
+	out    io.Writer
+	mutex  *sync.Mutex
+	buffer []byte
+}
+
+func (w *prefixWriter) Write(data []byte) (int, error) {
+	w.buffer = append(w.buffer, data...)
+	for {
+		end := bytes.IndexByte(w.buffer, '\n')
+		if end < 0 {
+			break
+		}
+		w.writeLine(w.buffer[:end+1])
+		w.buffer = w.buffer[end+1:]
+	}
+	return len(data), nil
+}
+
+// Flush writes what is left of a line that didn't end with a newline.
+func (w *prefixWriter) Flush() {
+	if len(w.buffer) > 0 {
+		w.writeLine(append(w.buffer, '\n'))
+		w.buffer = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.out.Write(append([]byte(w.prefix), line...))
+}
+
+// runParallel starts the commands in the block at the same time, at most limit of them (the number of CPUs if
+// no limit is given), and waits for all of them. The output of every command is prefixed with its number.
+// $NRUN_LAST_EXIT is set to the exit code of the first command that failed.
+func (r *scriptRunner) runParallel(node *scriptNode) error {
+	limit := runtime.NumCPU()
+	if len(node.args) > 0 {
+		limit, _ = strconv.Atoi(node.args)
+	}
+	shell, err := GetShell()
+	if err != nil {
+		return errors.New("Error: " + err.Error())
+	}
+	// Everything is rendered before the first command starts so a failing placeholder doesn't leave commands running
+	scripts := make([]string, len(node.body))
+	for index, child := range node.body {
+		if scripts[index], err = r.templateContext.Render(child.text); err != nil {
+			return fmt.Errorf("Failed with %s", err)
+		}
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan bool, limit)
+	failed := 0
+	var firstErr error
+	for index, script := range scripts {
+		prefix := "[" + strconv.Itoa(index+1) + "] "
+		if r.flagList.BeVerbose != nil && *r.flagList.BeVerbose {
+			fmt.Println(prefix+"Executing command", "\""+script+"\"")
+		}
+		wg.Add(1)
+		slots <- true
+		go func(prefix string, script string, ignoreErrors bool) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			stdout := &prefixWriter{prefix: prefix, out: os.Stdout, mutex: &mutex}
+			stderr := &prefixWriter{prefix: prefix, out: os.Stderr, mutex: &mutex}
+			cmd := exec.Command(shell, "-c", script)
+			cmd.Dir = r.path
+			cmd.Env = r.commandEnv(r.path, script)
+			cmd.Stdout = stdout
+			cmd.Stderr = stderr
+			err := r.interrupt.run(cmd)
+			stdout.Flush()
+			stderr.Flush()
+			if err != nil {
+				mutex.Lock()
+				if ignoreErrors {
+					fmt.Fprintln(os.Stderr, prefix+"Ignoring error:", err)
+				} else {
+					fmt.Fprintln(os.Stderr, prefix+"\""+script+"\" failed:", err)
+					if failed == 0 {
+						firstErr = err
+					}
+					failed++
+				}
+				mutex.Unlock()
+			}
+		}(prefix, script, node.body[index].ignoreErrors)
+	}
+	wg.Wait()
+	setLastExit(firstErr)
+	if failed > 0 {
+		return r.errorf(node, "%d of %d commands in @@parallel failed (%w)", failed, len(scripts), firstErr)
+	}
+	return nil
+}
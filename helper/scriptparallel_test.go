@@ -0,0 +1,70 @@
+package helper
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	writer := &prefixWriter{prefix: "[1] ", out: &out, mutex: &sync.Mutex{}}
+	writer.Write([]byte("one\ntw"))
+	if out.String() != "[1] one\n" {
+		t.Errorf("a partial line should be kept until it ends, got %q", out.String())
+	}
+	writer.Write([]byte("o\nthree"))
+	writer.Flush()
+	writer.Flush()
+	if expected := "[1] one\n[1] two\n[1] three\n"; out.String() != expected {
+		t.Errorf("output = %q, expected %q", out.String(), expected)
+	}
+}
+
+func TestScriptParallel(t *testing.T) {
+	dir := t.TempDir()
+	// Every command counts the commands that are running when it starts
+	count := "touch running.%d; ls running.* | wc -l >> counts; sleep 0.2; rm running.%d"
+	scripts := map[string][]string{
+		"limit":  {"@@parallel: 2"},
+		"output": {"@@parallel: 2", "printf 'one\\ntwo\\n'", "printf 'thr'; sleep 0.1; printf 'ee\\nfour'", "@@end", "echo done"},
+		"failed": {"@@parallel: 3", "sleep 0.3; exit 6", "exit 5", "- exit 9", "@@end", "echo not reached"},
+	}
+	for index := 1; index <= 5; index++ {
+		scripts["limit"] = append(scripts["limit"], strings.ReplaceAll(count, "%d", strconv.Itoa(index)))
+	}
+	scripts["limit"] = append(scripts["limit"], "@@endparallel")
+
+	if code, _ := runTestScript(t, dir, scripts, "limit"); code != 0 {
+		t.Fatalf("limit = %d, expected 0", code)
+	}
+	data, _ := os.ReadFile(dir + "/counts")
+	counts := strings.Fields(string(data))
+	if len(counts) != 5 {
+		t.Fatalf("expected 5 counts, got %q", counts)
+	}
+	for _, running := range counts {
+		if running != "1" && running != "2" {
+			t.Errorf("at most 2 commands should run at the same time, got %q", counts)
+			break
+		}
+	}
+
+	// Lines from different commands can come in any order but every line is whole and prefixed
+	code, output := runTestScript(t, dir, scripts, "output")
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	sort.Strings(lines)
+	if expected := []string{"[1] one", "[1] two", "[2] four", "[2] three", "done"}; code != 0 || strings.Join(lines, ",") != strings.Join(expected, ",") {
+		t.Errorf("output = %d, %q, expected 0, %q", code, lines, expected)
+	}
+
+	// The exit code is the one of the command that failed first, ignored commands don't count
+	code, output = runTestScript(t, dir, scripts, "failed")
+	if code != 5 || output != "" {
+		t.Errorf("failed = %d, %q, expected 5, \"\"", code, output)
+	}
+}
@@ -0,0 +1,268 @@
+package helper
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// scriptNode is a line of an nrun script or a block like @@if ... @@endif with the lines inside it.
+type scriptNode struct {
+	line      int
+	text      string
+	directive string
+	args      string
+	branches  []scriptBranch
+	body      []*scriptNode
+	// catch and finally of a @@try block
+	catch        []*scriptNode
+	hasCatch     bool
+	finally      []*scriptNode
+	ignoreErrors bool
+}
+
+// scriptBranch is the @@if, an @@elif or the @@else of a block, the condition is empty for @@else.
+type scriptBranch struct {
+	condition string
+	body      []*scriptNode
+}
+
+type scriptParser struct {
+	lines []string
+	pos   int
+	loops int
+}
+
+// scriptDirective splits "@@name: arguments" and "@@name arguments" into the name and the arguments.
+func scriptDirective(text string) (string, string) {
+	if !strings.HasPrefix(text, "@@") {
+		return "", ""
+	}
+	text = strings.TrimSpace(text[2:])
+	end := strings.IndexAny(text, ": \t")
+	if end < 0 {
+		return text, ""
+	}
+	return text[:end], strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text[end:]), ":"))
+}
+
+// parseScript turns the lines of an nrun script into blocks. The lines are parsed before they are rendered
+// so a var can't open or close a block.
+func parseScript(lines []string) ([]*scriptNode, error) {
+	parser := &scriptParser{lines: lines}
+	nodes, end, err := parser.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	if len(end) > 0 {
+		return nil, parser.unexpectedEnd(end)
+	}
+	return nodes, nil
+}
+
+func (p *scriptParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+// unexpectedEnd is the error for a directive that ends a block that wasn't started.
+func (p *scriptParser) unexpectedEnd(end string) error {
+	switch end {
+	case "endforeach", "endparallel":
+		return p.errorf("@@%s without @@%s", end, end[3:])
+	case "catch", "finally", "endtry":
+		return p.errorf("@@%s without @@try", end)
+	case "end":
+		return p.errorf("@@end without a block to end")
+	}
+	return p.errorf("@@%s without @@if", end)
+}
+
+// parseBlock reads lines until a line that ends the block and returns the name of that directive,
+// or "" at the end of the script.
+func (p *scriptParser) parseBlock() ([]*scriptNode, string, error) {
+	nodes := []*scriptNode{}
+	for p.pos < len(p.lines) {
+		text := p.lines[p.pos]
+		// A line starting with - doesn't stop the script when it fails
+		ignoreErrors := strings.HasPrefix(text, "-")
+		if ignoreErrors {
+			text = strings.TrimSpace(text[1:])
+		}
+		name, args := scriptDirective(text)
+		var node *scriptNode
+		var err error
+		switch name {
+		case "elif", "else", "endif", "endforeach", "endparallel", "catch", "finally", "endtry", "end":
+			if ignoreErrors {
+				return nil, "", p.errorf("@@%s can't be ignored", name)
+			}
+			return nodes, name, nil
+		case "if", "foreach", "parallel", "try":
+			if ignoreErrors {
+				return nil, "", p.errorf("errors of a @@%s block can't be ignored, use @@try", name)
+			}
+			switch name {
+			case "if":
+				node, err = p.parseIf(args)
+			case "foreach":
+				node, err = p.parseForeach(args)
+			case "parallel":
+				node, err = p.parseParallel(args)
+			default:
+				node, err = p.parseTry()
+			}
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, node)
+			continue
+		case "call", "ask", "choose", "confirm", "capture":
+			if len(args) == 0 {
+				return nil, "", p.errorf("@@%s needs arguments", name)
+			}
+			node = &scriptNode{directive: name, args: args}
+		case "break", "continue":
+			if p.loops == 0 {
+				return nil, "", p.errorf("@@%s outside of @@foreach", name)
+			}
+			node = &scriptNode{directive: name}
+		default:
+			node = &scriptNode{}
+		}
+		node.line, node.text, node.ignoreErrors = p.pos, text, ignoreErrors
+		nodes = append(nodes, node)
+		p.pos++
+	}
+	return nodes, "", nil
+}
+
+func (p *scriptParser) parseIf(condition string) (*scriptNode, error) {
+	node := &scriptNode{line: p.pos, text: p.lines[p.pos], directive: "if"}
+	if len(condition) == 0 {
+		return nil, p.errorf("@@if needs a condition")
+	}
+	start := p.pos
+	p.pos++
+	hasElse := false
+	for {
+		body, end, err := p.parseBlock()
+		if err != nil {
+			return nil, err
+		}
+		node.branches = append(node.branches, scriptBranch{condition: condition, body: body})
+		switch end {
+		case "":
+			p.pos = start
+			return nil, p.errorf("@@if without @@endif")
+		case "endif", "end":
+			p.pos++
+			return node, nil
+		case "endforeach", "endparallel", "catch", "finally", "endtry":
+			return nil, p.unexpectedEnd(end)
+		case "elif", "else":
+			if hasElse {
+				return nil, p.errorf("@@%s after @@else", end)
+			}
+			_, condition = scriptDirective(p.lines[p.pos])
+			if end == "else" {
+				hasElse = true
+				condition = ""
+			} else if len(condition) == 0 {
+				return nil, p.errorf("@@elif needs a condition")
+			}
+			p.pos++
+		}
+	}
+}
+
+// parseForeach reads "@@foreach name in list" and the lines up to @@endforeach or @@end.
+func (p *scriptParser) parseForeach(args string) (*scriptNode, error) {
+	if !foreachRegexp.MatchString(args) {
+		return nil, p.errorf("expected @@foreach name in list")
+	}
+	node := &scriptNode{line: p.pos, text: p.lines[p.pos], directive: "foreach", args: args}
+	start := p.pos
+	p.pos++
+	p.loops++
+	body, end, err := p.parseBlock()
+	p.loops--
+	if err != nil {
+		return nil, err
+	}
+	switch end {
+	case "endforeach", "end":
+		node.body = body
+		p.pos++
+		return node, nil
+	case "":
+		p.pos = start
+		return nil, p.errorf("@@foreach without @@endforeach")
+	}
+	return nil, p.unexpectedEnd(end)
+}
+
+// parseParallel reads "@@parallel" or "@@parallel: limit" and the commands up to @@endparallel or @@end.
+// Only commands can be run in parallel, the internal commands change the state of the script.
+func (p *scriptParser) parseParallel(args string) (*scriptNode, error) {
+	if limit, err := strconv.Atoi(args); len(args) > 0 && (err != nil || limit < 1) {
+		return nil, p.errorf("the limit of @@parallel has to be a number above 0")
+	}
+	node := &scriptNode{line: p.pos, text: p.lines[p.pos], directive: "parallel", args: args}
+	start := p.pos
+	for p.pos++; p.pos < len(p.lines); p.pos++ {
+		text := p.lines[p.pos]
+		ignoreErrors := strings.HasPrefix(text, "-")
+		if ignoreErrors {
+			text = strings.TrimSpace(text[1:])
+		}
+		if name, _ := scriptDirective(text); !ignoreErrors && (name == "endparallel" || name == "end") {
+			p.pos++
+			return node, nil
+		} else if strings.HasPrefix(text, "@@") {
+			return nil, p.errorf("only commands can be used in @@parallel, found %s", p.lines[p.pos])
+		}
+		node.body = append(node.body, &scriptNode{line: p.pos, text: text, ignoreErrors: ignoreErrors})
+	}
+	p.pos = start
+	return nil, p.errorf("@@parallel without @@endparallel")
+}
+
+// parseTry reads "@@try", the lines up to @@catch, @@finally or @@endtry (or @@end) and the lines of the
+// @@catch and @@finally parts. Both parts are optional and @@catch has to come first.
+func (p *scriptParser) parseTry() (*scriptNode, error) {
+	node := &scriptNode{line: p.pos, text: p.lines[p.pos], directive: "try"}
+	start := p.pos
+	p.pos++
+	part := "try"
+	for {
+		body, end, err := p.parseBlock()
+		if err != nil {
+			return nil, err
+		}
+		switch part {
+		case "try":
+			node.body = body
+		case "catch":
+			node.catch = body
+		case "finally":
+			node.finally = body
+		}
+		switch end {
+		case "":
+			p.pos = start
+			return nil, p.errorf("@@try without @@endtry")
+		case "endtry", "end":
+			p.pos++
+			return node, nil
+		case "catch", "finally":
+			if part == "finally" || part == end {
+				return nil, p.errorf("@@%s after @@%s", end, part)
+			}
+			part = end
+			node.hasCatch = node.hasCatch || end == "catch"
+			p.pos++
+		default:
+			return nil, p.unexpectedEnd(end)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package helper
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/shlex"
+)
+
+// ScriptAnswers holds the answers given with --answer name=value, the flag can be used more than once.
+type ScriptAnswers map[string]string
+
+func (a ScriptAnswers) String() string {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name+"="+a[name])
+	}
+	return strings.Join(parts, ",")
+}
+
+func (a ScriptAnswers) Set(value string) error {
+	name, answer, found := strings.Cut(value, "=")
+	if !found || !identifierRegexp.MatchString(name) {
+		return errors.New("expected name=value")
+	}
+	a[name] = answer
+	return nil
+}
+
+// scriptPrompt is an @@ask, @@choose or @@confirm directive.
+//
+//	@@ask: name ["question"] [default=value] [match=regexp]
+//	@@choose: name ["question"] a,b,c [default=b]
+//	@@confirm: [name] "question" [default=yes]
+//
+// A @@confirm without a name stops the script when the answer is no, with a name the answer is stored as
+// true or false.
+type scriptPrompt struct {
+	kind         string
+	name         string
+	question     string
+	choices      []string
+	defaultValue string
+	hasDefault   bool
+	match        *regexp.Regexp
+	gate         bool
+}
+
+func parseScriptPrompt(kind string, args string) (*scriptPrompt, error) {
+	words, err := shlex.Split(args)
+	if err != nil {
+		return nil, err
+	}
+	prompt := &scriptPrompt{kind: kind}
+	positional := []string{}
+	for _, word := range words {
+		switch {
+		case strings.HasPrefix(word, "default="):
+			prompt.defaultValue, prompt.hasDefault = word[8:], true
+		case strings.HasPrefix(word, "match=") && kind == "ask":
+			if prompt.match, err = regexp.Compile(word[6:]); err != nil {
+				return nil, err
+			}
+		default:
+			positional = append(positional, word)
+		}
+	}
+	usage := map[string]string{
+		"ask":     "@@ask: name [\"question\"] [default=value] [match=regexp]",
+		"choose":  "@@choose: name [\"question\"] a,b,c [default=value]",
+		"confirm": "@@confirm: [name] \"question\" [default=yes]",
+	}
+	switch {
+	case kind == "ask" && len(positional) >= 1 && len(positional) <= 2:
+		prompt.name, prompt.question = positional[0], positional[len(positional)-1]
+	case kind == "choose" && len(positional) >= 2 && len(positional) <= 3:
+		prompt.name, prompt.question = positional[0], positional[len(positional)-2]
+		for _, choice := range strings.Split(positional[len(positional)-1], ",") {
+			if choice = strings.TrimSpace(choice); len(choice) > 0 {
+				prompt.choices = append(prompt.choices, choice)
+			}
+		}
+		if len(prompt.choices) == 0 {
+			return nil, errors.New("@@choose needs at least one choice")
+		}
+	case kind == "confirm" && len(positional) == 1:
+		prompt.name, prompt.question, prompt.gate = "confirm", positional[0], true
+	case kind == "confirm" && len(positional) == 2:
+		prompt.name, prompt.question = positional[0], positional[1]
+	default:
+		return nil, errors.New("usage: " + usage[kind])
+	}
+	if !identifierRegexp.MatchString(prompt.name) {
+		return nil, fmt.Errorf("%q can't be used as a name", prompt.name)
+	}
+	if prompt.hasDefault {
+		if _, err := prompt.validate(prompt.defaultValue); err != nil {
+			return nil, fmt.Errorf("the default value is invalid: %s", err)
+		}
+	}
+	return prompt, nil
+}
+
+// validate checks an answer and returns the value that is stored. A choice can also be given by its number and a
+// confirmation is stored as true or false.
+func (p *scriptPrompt) validate(answer string) (string, error) {
+	switch p.kind {
+	case "choose":
+		for index, choice := range p.choices {
+			if answer == choice || answer == strconv.Itoa(index+1) {
+				return choice, nil
+			}
+		}
+		return "", fmt.Errorf("%q is not one of %s", answer, strings.Join(p.choices, ", "))
+	case "confirm":
+		switch strings.ToLower(answer) {
+		case "y", "yes", "true":
+			return "true", nil
+		case "n", "no", "false":
+			return "false", nil
+		}
+		return "", fmt.Errorf("%q is not yes or no", answer)
+	}
+	if p.match != nil && !p.match.MatchString(answer) {
+		return "", fmt.Errorf("%q doesn't match %s", answer, p.match)
+	}
+	return answer, nil
+}
+
+// readAnswer prints the question and reads a line from the terminal. It gives up when interrupted is closed.
+func readAnswer(reader *bufio.Reader, question string, interrupted <-chan bool) (string, error) {
+	fmt.Print(question)
+	type line struct {
+		text string
+		err  error
+	}
+	lines := make(chan line, 1)
+	go func() {
+		text, err := reader.ReadString('\n')
+		lines <- line{text, err}
+	}()
+	select {
+	case answer := <-lines:
+		if answer.err == io.EOF && len(answer.text) == 0 {
+			fmt.Println()
+			return "", errors.New("no answer")
+		}
+		return strings.TrimRight(answer.text, "\r\n"), nil
+	case <-interrupted:
+		fmt.Println()
+		return "", errors.New("no answer")
+	}
+}
+
+// ask asks the question on the terminal until the answer is valid.
+func (p *scriptPrompt) ask(interrupted <-chan bool) (string, error) {
+	reader := bufio.NewReader(os.Stdin)
+	question := p.question
+	defaultValue, hasDefault := p.defaultValue, p.hasDefault
+	switch p.kind {
+	case "choose":
+		fmt.Println(question)
+		defaultNumber := ""
+		for index, choice := range p.choices {
+			if p.hasDefault && choice == p.defaultValue {
+				defaultNumber = strconv.Itoa(index + 1)
+			}
+			fmt.Printf("  %d) %s\n", index+1, choice)
+		}
+		question = "Choice"
+		if len(defaultNumber) > 0 {
+			question += " [" + defaultNumber + "]"
+		}
+	case "confirm":
+		// Without a default the answer is no when just pressing enter
+		if value, _ := p.validate(defaultValue); value == "true" {
+			question += " [Y/n]"
+		} else {
+			question += " [y/N]"
+			defaultValue, hasDefault = "no", true
+		}
+	default:
+		if p.hasDefault {
+			question += " [" + p.defaultValue + "]"
+		}
+	}
+	for {
+		answer, err := readAnswer(reader, question+": ", interrupted)
+		if err != nil {
+			return "", err
+		}
+		if len(answer) == 0 && hasDefault {
+			answer = defaultValue
+		}
+		value, err := p.validate(answer)
+		if err == nil {
+			return value, nil
+		}
+		fmt.Println(err)
+	}
+}
+
+// answer returns the answer given with --answer or NRUN_ANSWER_name, asks for it on the terminal or uses the
+// default value when there is no terminal.
+func (p *scriptPrompt) answer(flagList *FlagList, interrupted <-chan bool) (string, error) {
+	answer, found := flagList.Answers[p.name]
+	if !found {
+		answer, found = os.LookupEnv("NRUN_ANSWER_" + p.name)
+	}
+	if !found && p.kind == "confirm" && flagList.AssumeYes != nil && *flagList.AssumeYes {
+		answer, found = "yes", true
+	}
+	if found {
+		return p.validate(answer)
+	}
+	if IsTerminal(os.Stdin) {
+		return p.ask(interrupted)
+	}
+	if p.hasDefault {
+		return p.validate(p.defaultValue)
+	}
+	if p.kind == "confirm" {
+		return "", errors.New("\"" + p.question + "\" needs an answer, use --yes, --answer " + p.name + "=yes or NRUN_ANSWER_" + p.name + "=yes without a terminal")
+	}
+	return "", errors.New("\"" + p.question + "\" needs an answer, use --answer " + p.name + "=value or NRUN_ANSWER_" + p.name + "=value without a terminal")
+}
@@ -0,0 +1,56 @@
+package helper
+
+import (
+	"testing"
+)
+
+func TestScriptPrompt(t *testing.T) {
+	tests := []struct {
+		kind     string
+		args     string
+		answer   string
+		expected string
+	}{
+		{"ask", `version "Version?" 'match=^[0-9.]+$'`, "1.2.3", "1.2.3"},
+		{"ask", `version "Version?" 'match=^[0-9.]+$'`, "v1", ""},
+		{"ask", `name`, "anything", "anything"},
+		{"choose", `target "Deploy to" dev,test,prod`, "prod", "prod"},
+		{"choose", `target dev,test,prod`, "2", "test"},
+		{"choose", `target dev,test,prod`, "live", ""},
+		{"confirm", `"Sure?"`, "Y", "true"},
+		{"confirm", `notify "Notify?"`, "no", "false"},
+		{"confirm", `notify "Notify?"`, "maybe", ""},
+	}
+	for _, test := range tests {
+		prompt, err := parseScriptPrompt(test.kind, test.args)
+		if err != nil {
+			t.Errorf("parseScriptPrompt(%q, %q) returned error %v", test.kind, test.args, err)
+			continue
+		}
+		value, err := prompt.validate(test.answer)
+		if test.expected == "" {
+			if err == nil {
+				t.Errorf("%s %s: %q should be invalid", test.kind, test.args, test.answer)
+			}
+		} else if value != test.expected || err != nil {
+			t.Errorf("%s %s: %q = %q, %v, expected %q", test.kind, test.args, test.answer, value, err, test.expected)
+		}
+	}
+
+	prompt, _ := parseScriptPrompt("confirm", `"Sure?"`)
+	if !prompt.gate || prompt.name != "confirm" {
+		t.Errorf("a @@confirm without a name should stop the script, got %+v", prompt)
+	}
+	for _, invalid := range []struct{ kind, args string }{
+		{"ask", `a b c`},
+		{"ask", `"not a name"`},
+		{"choose", `target`},
+		{"choose", `target dev,test default=prod`},
+		{"confirm", `a b c`},
+		{"ask", `version match=(`},
+	} {
+		if _, err := parseScriptPrompt(invalid.kind, invalid.args); err == nil {
+			t.Errorf("parseScriptPrompt(%q, %q) should return an error", invalid.kind, invalid.args)
+		}
+	}
+}
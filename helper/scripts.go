@@ -1,18 +1,23 @@
 package helper
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
-	"os/user"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
+
+	"github.com/google/shlex"
 )
 
-func ExecuteScriptList(script string, scripts map[string][]string, args []string, projects map[string]string, flagList *FlagList) {
+// ExecuteScriptList runs the script in every project and returns the exit code of the last one that failed.
+func ExecuteScriptList(script string, scripts map[string][]string, args []string, projects map[string]string, flagList *FlagList) int {
+	exitCode := 0
 	if len(scripts) > 0 && len(scripts[script]) > 0 {
 		for projectName, projectPath := range projects {
 			if flagList.BeVerbose != nil && *flagList.BeVerbose == true {
@@ -21,7 +26,13 @@ func ExecuteScriptList(script string, scripts map[string][]string, args []string
 				fmt.Println("  in project", projectName, "at", projectPath)
 				fmt.Println("================================================================================")
 			}
-			ExecuteScripts(projectPath, script, scripts[script], args, flagList)
+			if code := ExecuteScripts(projectPath, script, scripts, args, flagList); code != 0 {
+				exitCode = code
+				// The other projects are skipped after SIGINT or SIGTERM
+				if code == 128+int(syscall.SIGINT) || code == 128+int(syscall.SIGTERM) {
+					return exitCode
+				}
+			}
 			if flagList.BeVerbose != nil && *flagList.BeVerbose == true {
 				fmt.Println("================================================================================")
 			}
@@ -29,11 +40,18 @@ func ExecuteScriptList(script string, scripts map[string][]string, args []string
 	} else {
 		log.Println("No script found")
 	}
+	return exitCode
 }
 
-func ScriptRunner(scripts []string, wg *sync.WaitGroup) {
+func ScriptRunner(scripts []string, vars map[string]string, wg *sync.WaitGroup) {
 	defer wg.Done()
+	templateContext := NewTemplateContext(vars)
 	for _, script := range scripts {
+		script, err := templateContext.Render(script)
+		if err != nil {
+			log.Println("Failed with", err)
+			return
+		}
 		shell, shellErr := GetShell()
 		if shellErr != nil {
 			log.Println("Error:", shellErr)
@@ -54,9 +72,11 @@ func ScriptRunner(scripts []string, wg *sync.WaitGroup) {
 }
 
 func ExecuteMultipleScripts(scripts []string, flagList *FlagList) {
-	usr, _ := user.Current()
-	homeDir := usr.HomeDir
-	config, _ := ReadConfig(homeDir + "/.nrun.json")
+	config, err := GetEffectiveConfig("")
+	if err != nil {
+		log.Println("Failed with", err)
+		return
+	}
 	ApplyVarsArray(config.Scripts, config.Vars)
 	var wg sync.WaitGroup
 	for _, script := range scripts {
@@ -64,7 +84,7 @@ func ExecuteMultipleScripts(scripts []string, flagList *FlagList) {
 			fmt.Println("Executing script", script)
 		}
 		if len(config.Scripts[script]) > 0 {
-			go ScriptRunner(config.Scripts[script], &wg)
+			go ScriptRunner(config.Scripts[script], config.Vars, &wg)
 			wg.Add(1)
 		} else {
 			log.Println("No script found for command", script)
@@ -73,167 +93,463 @@ func ExecuteMultipleScripts(scripts []string, flagList *FlagList) {
 	wg.Wait()
 }
 
-func ExecuteScripts(path string, scriptName string, scripts []string, args []string, flagList *FlagList) {
+// errScriptStopped is returned when a check like @@hasfile ends the script, it isn't an error
+var errScriptStopped = errors.New("script stopped")
+
+type scriptRunner struct {
+	path            string
+	scriptName      string
+	args            []string
+	scripts         map[string][]string
+	calls           []string
+	flagList        *FlagList
+	vars            map[string]string
+	templateContext *TemplateContext
+	interrupt       *scriptInterrupt
+}
+
+// ExecuteScripts runs the nrun script scriptName, the other scripts can be run from it with @@call. The exit code
+// is the one of the command that stopped the script, or 1 if it was stopped by something else than a command.
+func ExecuteScripts(path string, scriptName string, scripts map[string][]string, args []string, flagList *FlagList) int {
+	// Outside of a project there is no package.json path, the script runs in the current directory
+	if len(path) == 0 {
+		path, _ = os.Getwd()
+	}
 	if flagList.BeVerbose != nil && *flagList.BeVerbose {
 		fmt.Println("Executing script", "\""+scriptName+"\"", "in", path)
 	}
-	if len(scripts) > 0 {
+	if len(scripts[scriptName]) > 0 {
+		nodes, err := parseScript(scripts[scriptName])
+		if err != nil {
+			log.Println("Invalid script", "\""+scriptName+"\":", err)
+			return 1
+		}
 		os.Chdir(path)
-		for _, script := range scripts {
-			if flagList.BeVerbose != nil && *flagList.BeVerbose {
-				fmt.Println("Executing command", "\""+script+"\"")
+		// Loops add their variable to the vars, so the runner gets its own copy
+		vars := make(map[string]string, len(flagList.Vars))
+		for key, value := range flagList.Vars {
+			vars[key] = value
+		}
+		runner := &scriptRunner{path: path, scriptName: scriptName, args: args, scripts: scripts, calls: []string{scriptName}, flagList: flagList, vars: vars, templateContext: NewTemplateContext(vars)}
+		runner.interrupt = watchScriptSignals()
+		defer runner.interrupt.stop()
+		if err := runner.runBlock(nodes); err != nil && err != errScriptStopped {
+			log.Println(err)
+			return exitCodeOf(err)
+		}
+	}
+	return 0
+}
+
+// exitCodeOf returns the exit code of the command that caused the error, or 1 for other errors.
+func exitCodeOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	var interrupted *interruptedError
+	if errors.As(err, &interrupted) {
+		return interrupted.exitCode()
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return exitErr.ExitCode()
+	}
+	return 1
+}
+
+// isScriptControl reports if the error is a @@break, @@continue or a check that stopped the script.
+func isScriptControl(err error) bool {
+	return err == errScriptStopped || err == errLoopBreak || err == errLoopContinue
+}
+
+// setLastExit makes the exit code of the last command available as $NRUN_LAST_EXIT.
+func setLastExit(err error) {
+	os.Setenv("NRUN_LAST_EXIT", strconv.Itoa(exitCodeOf(err)))
+}
+
+// errorf returns an error that tells in which script and on which line it happened, %w can be used to keep the
+// exit code of a command.
+func (r *scriptRunner) errorf(node *scriptNode, format string, args ...interface{}) error {
+	return fmt.Errorf("script %q line %d: "+format, append([]interface{}{r.scriptName, node.line + 1}, args...)...)
+}
+
+func (r *scriptRunner) runBlock(nodes []*scriptNode) error {
+	for _, node := range nodes {
+		if err := r.runNode(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *scriptRunner) runNode(node *scriptNode) error {
+	err := r.runDirective(node)
+	// After SIGINT or SIGTERM the script stops, only @@finally is still run
+	if interrupted := r.interrupt.err(); interrupted != nil {
+		return interrupted
+	}
+	if err != nil && node.ignoreErrors && !isScriptControl(err) {
+		fmt.Fprintln(os.Stderr, "Ignoring error:", err)
+		return nil
+	}
+	return err
+}
+
+func (r *scriptRunner) runDirective(node *scriptNode) error {
+	switch node.directive {
+	case "try":
+		return r.runTry(node)
+	case "foreach":
+		return r.runForeach(node)
+	case "call":
+		return r.runCall(node)
+	case "parallel":
+		return r.runParallel(node)
+	case "ask", "choose", "confirm":
+		return r.runPrompt(node)
+	case "capture":
+		return r.runCapture(node)
+	case "break":
+		return errLoopBreak
+	case "continue":
+		return errLoopContinue
+	case "if":
+		return r.runIf(node)
+	}
+	return r.runLine(node.text)
+}
+
+func (r *scriptRunner) runIf(node *scriptNode) error {
+	for _, branch := range node.branches {
+		if len(branch.condition) > 0 {
+			condition, err := r.templateContext.Render(branch.condition)
+			if err != nil {
+				return fmt.Errorf("Failed with %s", err)
 			}
-			if len(script) > 2 {
-				if script[0:2] == "@@" {
-					doContinue := true
-					script = script[2:]
-					negate := false
-					if len(script) > 0 && script[0] == '!' {
-						negate = true
-						script = script[1:]
-					}
-					if strings.Contains(script, ":") {
-						commandParts := strings.Split(script, ":")
-						if len(commandParts) > 1 {
-							commandName := commandParts[0]
-							commandArgs := strings.Join(commandParts[1:], ":")
-							if commandName == "hasfile" || commandName == "hasfiles" {
-								files := strings.Split(commandArgs, ",")
-								fileFound := false
-								for _, file := range files {
-									file = strings.TrimSpace(file)
-									if len(file) > 0 {
-										if file[0] != '/' {
-											file = path + "/" + file
-										}
-									}
-									if FileExists(file) {
-										fileFound = true
-									} else if commandName == "hasfiles" {
-										if negate {
-											continue
-										}
-										return
-									}
+			matched, err := EvalCondition(condition, r.path, r.args)
+			if err != nil {
+				return r.errorf(node, "%s: %s", condition, err)
+			}
+			if r.flagList.BeVerbose != nil && *r.flagList.BeVerbose {
+				fmt.Println("Condition", "\""+condition+"\"", "is", matched)
+			}
+			if !matched {
+				continue
+			}
+		}
+		return r.runBlock(branch.body)
+	}
+	return nil
+}
+
+// runTry runs the @@try part and the @@catch part if it fails. The @@finally part is always run, even when the
+// script is stopped or a loop is left, and an error in it replaces the one from before. Without @@catch the
+// error is passed on after @@finally. In @@catch the error is available as $NRUN_ERROR.
+func (r *scriptRunner) runTry(node *scriptNode) error {
+	err := r.runBlock(node.body)
+	if err != nil && !isScriptControl(err) && r.interrupt.err() == nil && node.hasCatch {
+		if r.flagList.BeVerbose != nil && *r.flagList.BeVerbose {
+			fmt.Println("Caught", err)
+		}
+		setLastExit(err)
+		os.Setenv("NRUN_ERROR", err.Error())
+		err = r.runBlock(node.catch)
+		os.Unsetenv("NRUN_ERROR")
+	}
+	// @@finally also runs when the script got SIGINT or SIGTERM
+	received := r.interrupt.pause()
+	finallyErr := r.runBlock(node.finally)
+	r.interrupt.resume(received)
+	if finallyErr != nil {
+		return finallyErr
+	}
+	return err
+}
+
+// runForeach runs the body for every item, the variable is available as {{name}} and as an environment variable.
+func (r *scriptRunner) runForeach(node *scriptNode) error {
+	match := foreachRegexp.FindStringSubmatch(node.args)
+	name := match[1]
+	list, err := r.templateContext.Render(match[2])
+	if err != nil {
+		return fmt.Errorf("Failed with %s", err)
+	}
+	items, err := expandForeachItems(list, r.path)
+	if err != nil {
+		return r.errorf(node, "%s", err)
+	}
+	path := r.path
+	oldValue, hadValue := r.vars[name]
+	oldEnv, hadEnv := os.LookupEnv(name)
+	defer func() {
+		if hadValue {
+			r.vars[name] = oldValue
+		} else {
+			delete(r.vars, name)
+		}
+		if hadEnv {
+			os.Setenv(name, oldEnv)
+		} else {
+			os.Unsetenv(name)
+		}
+		r.path = path
+		os.Chdir(path)
+	}()
+	for _, item := range items {
+		if err := os.Chdir(item.dir); err != nil {
+			return r.errorf(node, "%s", err)
+		}
+		r.path = item.dir
+		r.vars[name] = escapeTemplate(item.value)
+		os.Setenv(name, item.value)
+		if r.flagList.BeVerbose != nil && *r.flagList.BeVerbose {
+			fmt.Println("Iteration", name+"="+item.value, "in", item.dir)
+		}
+		err := r.runBlock(node.body)
+		if err == errLoopBreak {
+			break
+		}
+		if err != nil && err != errLoopContinue {
+			return err
+		}
+	}
+	return nil
+}
+
+// runCall runs another nrun script with the same vars and environment. The directory is restored afterwards and
+// a check like @@hasfile in the called script only ends that script.
+func (r *scriptRunner) runCall(node *scriptNode) error {
+	call, err := r.templateContext.Render(node.args)
+	if err != nil {
+		return fmt.Errorf("Failed with %s", err)
+	}
+	parts, err := shlex.Split(call)
+	if err != nil || len(parts) == 0 {
+		return r.errorf(node, "invalid @@call %q", call)
+	}
+	name := parts[0]
+	for _, caller := range r.calls {
+		if caller == name {
+			return r.errorf(node, "@@call cycle: %s -> %s", strings.Join(r.calls, " -> "), name)
+		}
+	}
+	if len(r.scripts[name]) == 0 {
+		return r.errorf(node, "the script %q doesn't exist", name)
+	}
+	nodes, err := parseScript(r.scripts[name])
+	if err != nil {
+		return fmt.Errorf("Invalid script %q: %s", name, err)
+	}
+	if r.flagList.BeVerbose != nil && *r.flagList.BeVerbose {
+		fmt.Println("Calling script", "\""+name+"\"", strings.Join(parts[1:], " "))
+	}
+	path, scriptName, args := r.path, r.scriptName, r.args
+	r.scriptName, r.args, r.calls = name, parts[1:], append(r.calls, name)
+	err = r.runBlock(nodes)
+	r.scriptName, r.args, r.calls = scriptName, args, r.calls[:len(r.calls)-1]
+	r.path = path
+	os.Chdir(path)
+	if err == errScriptStopped {
+		return nil
+	}
+	return err
+}
+
+// runPrompt asks a question and stores the answer as a var and an environment variable for the rest of the script.
+func (r *scriptRunner) runPrompt(node *scriptNode) error {
+	args, err := r.templateContext.Render(node.args)
+	if err != nil {
+		return fmt.Errorf("Failed with %s", err)
+	}
+	prompt, err := parseScriptPrompt(node.directive, args)
+	if err != nil {
+		return r.errorf(node, "%s", err)
+	}
+	answer, err := prompt.answer(r.flagList, r.interrupt.done())
+	if err != nil {
+		return r.errorf(node, "%s", err)
+	}
+	if prompt.gate {
+		if answer != "true" {
+			fmt.Println("Stopped")
+			return errScriptStopped
+		}
+		return nil
+	}
+	r.vars[prompt.name] = escapeTemplate(answer)
+	os.Setenv(prompt.name, answer)
+	if r.flagList.BeVerbose != nil && *r.flagList.BeVerbose {
+		fmt.Println("Answer", prompt.name+"="+answer)
+	}
+	return nil
+}
+
+// commandEnv returns the environment for a command of the script.
+func (r *scriptRunner) commandEnv(path string, script string) []string {
+	env := os.Environ()
+	env = append(env, []string{"NRUN_CURRENT_PATH=" + path}...)
+	env = append(env, []string{"NRUN_CURRENT_SCRIPT=" + r.scriptName}...)
+	env = append(env, []string{"NRUN_CURRENT_SCRIPT_CODE=" + script}...)
+	for i, arg := range r.args {
+		env = append(env, []string{"NRUN_ARG_" + strconv.Itoa(i) + "=" + arg}...)
+	}
+	return env
+}
+
+func (r *scriptRunner) runLine(script string) error {
+	// @@cd changes the path for the rest of the script
+	path := r.path
+	defer func() {
+		r.path = path
+	}()
+	script, err := r.templateContext.Render(script)
+	if err != nil {
+		return fmt.Errorf("Failed with %s", err)
+	}
+	if r.flagList.BeVerbose != nil && *r.flagList.BeVerbose {
+		fmt.Println("Executing command", "\""+script+"\"")
+	}
+	if len(script) > 2 {
+		if script[0:2] == "@@" {
+			doContinue := true
+			script = script[2:]
+			negate := false
+			if len(script) > 0 && script[0] == '!' {
+				negate = true
+				script = script[1:]
+			}
+			if strings.Contains(script, ":") {
+				commandParts := strings.Split(script, ":")
+				if len(commandParts) > 1 {
+					commandName := commandParts[0]
+					commandArgs := strings.Join(commandParts[1:], ":")
+					if commandName == "hasfile" || commandName == "hasfiles" {
+						files := strings.Split(commandArgs, ",")
+						fileFound := false
+						for _, file := range files {
+							file = strings.TrimSpace(file)
+							if len(file) > 0 {
+								if file[0] != '/' {
+									file = path + "/" + file
 								}
-								if !fileFound {
-									if negate {
-										continue
-									}
-									return
-								} else if negate {
-									return
+							}
+							if FileExists(file) {
+								fileFound = true
+							} else if commandName == "hasfiles" {
+								if negate {
+									continue
 								}
-							} else if commandName == "cd" {
-								commandArgs = strings.TrimSpace(commandArgs)
-								if len(commandArgs) > 0 {
-									if commandArgs[0] == '@' {
-										// chdir into project
-										projectName := commandArgs[1:]
-										if len(projectName) > 0 {
-											usr, _ := user.Current()
-											dir := usr.HomeDir
-											config, _ := ReadConfig(dir + "/.nrun.json")
-											if projectPath, ok := config.Projects[projectName]; ok {
-												os.Chdir(projectPath)
-												path, _ = os.Getwd()
-											}
-										}
-									} else if commandArgs[0] != '/' {
-										os.Chdir(path + "/" + commandArgs)
-										path, _ = os.Getwd()
-									} else {
-										os.Chdir(commandArgs)
+								return errScriptStopped
+							}
+						}
+						if !fileFound {
+							if negate {
+								return nil
+							}
+							return errScriptStopped
+						} else if negate {
+							return errScriptStopped
+						}
+					} else if commandName == "cd" {
+						commandArgs = strings.TrimSpace(commandArgs)
+						if len(commandArgs) > 0 {
+							if commandArgs[0] == '@' {
+								// chdir into project
+								projectName := commandArgs[1:]
+								if len(projectName) > 0 {
+									config, _ := GetEffectiveConfig("")
+									if config != nil && len(config.Projects[projectName]) > 0 {
+										os.Chdir(config.Projects[projectName])
 										path, _ = os.Getwd()
 									}
 								}
-							} else if commandName == "set" || commandName == "env" {
-								commandArgs = strings.TrimSpace(commandArgs)
-								if strings.Contains(commandArgs, "=") {
-									commandParts := strings.Split(commandArgs, "=")
-									if len(commandParts) > 1 {
-										os.Setenv(commandParts[0], strings.Join(commandParts[1:], "="))
-									}
-								}
-							} else if commandName == "unset" || commandName == "unenv" {
-								commandArgs = strings.TrimSpace(commandArgs)
-								os.Unsetenv(commandArgs)
-							} else if commandName == "echo" {
-								commandArgs = strings.TrimSpace(commandArgs)
-								fmt.Println(commandArgs)
-							} else if commandName == "isfile" {
-								commandArgs = strings.TrimSpace(commandArgs)
-								if len(commandArgs) > 0 {
-									if commandArgs[0] != '/' {
-										commandArgs = path + "/" + commandArgs
-									}
-									if !IsFile(commandArgs) {
-										if negate {
-											continue
-										}
-										return
-									} else if negate {
-										return
-									}
+							} else if commandArgs[0] != '/' {
+								os.Chdir(path + "/" + commandArgs)
+								path, _ = os.Getwd()
+							} else {
+								os.Chdir(commandArgs)
+								path, _ = os.Getwd()
+							}
+						}
+					} else if commandName == "set" || commandName == "env" {
+						commandArgs = strings.TrimSpace(commandArgs)
+						if strings.Contains(commandArgs, "=") {
+							commandParts := strings.Split(commandArgs, "=")
+							if len(commandParts) > 1 {
+								os.Setenv(commandParts[0], strings.Join(commandParts[1:], "="))
+							}
+						}
+					} else if commandName == "unset" || commandName == "unenv" {
+						commandArgs = strings.TrimSpace(commandArgs)
+						os.Unsetenv(commandArgs)
+					} else if commandName == "echo" {
+						commandArgs = strings.TrimSpace(commandArgs)
+						fmt.Println(commandArgs)
+					} else if commandName == "isfile" {
+						commandArgs = strings.TrimSpace(commandArgs)
+						if len(commandArgs) > 0 {
+							if commandArgs[0] != '/' {
+								commandArgs = path + "/" + commandArgs
+							}
+							if !IsFile(commandArgs) {
+								if negate {
+									return nil
 								}
-							} else if commandName == "isdir" {
-								commandArgs = strings.TrimSpace(commandArgs)
-								if len(commandArgs) > 0 {
-									if commandArgs[0] != '/' {
-										commandArgs = path + "/" + commandArgs
-									}
-									if !IsDir(commandArgs) {
-										if negate {
-											continue
-										}
-										return
-									} else if negate {
-										return
-									}
+								return errScriptStopped
+							} else if negate {
+								return errScriptStopped
+							}
+						}
+					} else if commandName == "isdir" {
+						commandArgs = strings.TrimSpace(commandArgs)
+						if len(commandArgs) > 0 {
+							if commandArgs[0] != '/' {
+								commandArgs = path + "/" + commandArgs
+							}
+							if !IsDir(commandArgs) {
+								if negate {
+									return nil
 								}
+								return errScriptStopped
+							} else if negate {
+								return errScriptStopped
 							}
 						}
-					} else {
-						log.Println("Invalid command:", script)
-						return
-					}
-					if doContinue {
-						continue
 					}
 				}
+			} else {
+				return errors.New("Invalid command: " + script)
 			}
-			shell, shellErr := GetShell()
-			if shellErr != nil {
-				log.Println("Error:", shellErr)
-				return
-			}
-			cmd := exec.Command(shell, append([]string{"-c", script})...)
-
-			env := os.Environ()
-			env = append(env, []string{"NRUN_CURRENT_PATH=" + path}...)
-			env = append(env, []string{"NRUN_CURRENT_SCRIPT=" + scriptName}...)
-			env = append(env, []string{"NRUN_CURRENT_SCRIPT_CODE=" + script}...)
-			for i, arg := range args {
-				env = append(env, []string{"NRUN_ARG_" + strconv.Itoa(i) + "=" + arg}...)
-			}
-			cmd.Env = env
-
-			cmd.Stdout = os.Stdout
-			cmd.Stdin = os.Stdin
-			cmd.Stderr = os.Stderr
-
-			runErr := cmd.Run()
-			if runErr != nil {
-				log.Println(runErr)
-				return
+			if doContinue {
+				return nil
 			}
 		}
 	}
+	shell, shellErr := GetShell()
+	if shellErr != nil {
+		return errors.New("Error: " + shellErr.Error())
+	}
+	cmd := exec.Command(shell, append([]string{"-c", script})...)
+	cmd.Env = r.commandEnv(path, script)
+
+	cmd.Stdout = os.Stdout
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	err = r.interrupt.run(cmd)
+	setLastExit(err)
+	return err
 }
 
-func ShowScript(packageJSON PackageJSON, script string) {
+func ShowScript(packageJSON PackageJSON, script string, path string, flagList *FlagList) {
 	if len(packageJSON.Scripts) > 0 && len(packageJSON.Scripts[script]) > 0 {
 		fmt.Printf("%s -> %s\n", script, packageJSON.Scripts[script])
+		if flagList.BeVerbose != nil && *flagList.BeVerbose {
+			fmt.Println("The script will be executed with PATH:")
+			for _, pathPart := range strings.Split(GetScriptPath(path), string(os.PathListSeparator)) {
+				fmt.Println("  " + pathPart)
+			}
+		}
 	} else {
 		fmt.Printf("Can't find any script called \"%s\"\n", script)
 	}
@@ -291,57 +607,42 @@ func ListExecutableScripts(scripts map[string][]string, flagList *FlagList) {
 }
 
 func AddToExecutableScript(args []string, flagList *FlagList) {
-	usr, _ := user.Current()
-	dir := usr.HomeDir
-	config, err := ReadConfig(dir + "/.nrun.json")
-	err = CopyFile(dir+"/.nrun.json", dir+"/.nrun.json.bak")
+	err := UpdateConfig(GetGlobalConfigFile(), func(config *Config) error {
+		if config.Scripts == nil {
+			config.Scripts = make(map[string][]string)
+		}
+		config.Scripts[*flagList.AddToExecutableScript] = append(config.Scripts[*flagList.AddToExecutableScript], strings.Join(args, " "))
+		return nil
+	})
 	if err != nil {
 		log.Println("Failed with", err)
-	} else {
-		var commands []string
-		if config.Scripts != nil && config.Scripts[*flagList.AddToExecutableScript] != nil {
-			commands = config.Scripts[*flagList.AddToExecutableScript]
-		}
-		commands = append(commands, strings.Join(args, " "))
-		config.Scripts[*flagList.AddToExecutableScript] = commands
-		err := WriteConfig(dir+"/.nrun.json", config)
-		if err != nil {
-			log.Println("Failed with", err)
-			return
-		}
-		log.Println("Command added to the executable script", "\""+*flagList.AddToExecutableScript+"\"")
+		return
 	}
+	log.Println("Command added to the executable script", "\""+*flagList.AddToExecutableScript+"\"")
 }
 
 func RemoveExecutableScript(script string, args []string) {
-	usr, _ := user.Current()
-	dir := usr.HomeDir
-	config, err := ReadConfig(dir + "/.nrun.json")
-
-	err = CopyFile(dir+"/.nrun.json", dir+"/.nrun.json.bak")
+	removed := false
+	err := UpdateConfig(GetGlobalConfigFile(), func(config *Config) error {
+		if config.Scripts[script] == nil {
+			return ErrConfigUnchanged
+		}
+		delete(config.Scripts, script)
+		removed = true
+		return nil
+	})
 	if err != nil {
 		log.Println("Failed with", err)
+		return
+	}
+	if removed {
+		log.Println("Executable script \"" + script + "\" has been removed")
 	} else {
-		if config.Scripts[script] == nil {
-			log.Println("The script \"" + script + "\" doesn't exist")
-			if len(args) > 0 {
-				script = args[0]
-				args = args[1:]
-				RemoveExecutableScript(script, args)
-			}
-		} else {
-			delete(config.Scripts, script)
-			err := WriteConfig(dir+"/.nrun.json", config)
-			if err != nil {
-				log.Println("Failed with", err)
-				return
-			}
-			log.Println("Executable script \"" + script + "\" has been removed")
-			if len(args) > 0 {
-				script = args[0]
-				args = args[1:]
-				RemoveExecutableScript(script, args)
-			}
-		}
+		log.Println("The script \"" + script + "\" doesn't exist")
+	}
+	if len(args) > 0 {
+		script = args[0]
+		args = args[1:]
+		RemoveExecutableScript(script, args)
 	}
 }
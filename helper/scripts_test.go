@@ -0,0 +1,122 @@
+package helper
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+// runTestScript runs the script through ExecuteScripts and returns the exit code and what the script wrote
+// to stdout.
+func runTestScript(t *testing.T, path string, scripts map[string][]string, name string, args ...string) (int, string) {
+	cwd, _ := os.Getwd()
+	stdout := os.Stdout
+	output, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer output.Close()
+	t.Setenv("NRUN_LAST_EXIT", "")
+	t.Setenv("NRUN_ERROR", "")
+	os.Stdout = output
+	code := ExecuteScripts(path, name, scripts, args, &FlagList{})
+	os.Stdout = stdout
+	os.Chdir(cwd)
+	output.Seek(0, io.SeekStart)
+	data, _ := io.ReadAll(output)
+	return code, string(data)
+}
+
+func TestExecuteScriptsWithoutPath(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(dir+"/marker", []byte{}, 0644)
+	cwd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(cwd)
+
+	// Without a package.json the script runs in the current directory
+	scripts := map[string][]string{"t": {"@@hasfile:marker", "pwd", "@@foreach item in a", "pwd", "@@end"}}
+	code, output := runTestScript(t, "", scripts, "t")
+	if expected := dir + "\n" + dir + "\n"; code != 0 || output != expected {
+		t.Errorf("ExecuteScripts() = %d, %q, expected 0, %q", code, output, expected)
+	}
+}
+
+func TestScriptCall(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(dir+"/sub", 0755)
+	scripts := map[string][]string{
+		"t1": {"@@call: t2 one \"two words\"", "pwd", "echo \"back $NRUN_ARG_0\""},
+		// A failing check only ends the called script and the caller continues where it was
+		"t2": {"echo \"$NRUN_ARG_0|$NRUN_ARG_1\"", "@@cd:sub", "pwd", "@@hasfile:missing", "echo not reached"},
+		"t3": {"echo start", "@@call: t4"},
+		"t4": {"@@call: t3", "echo not reached"},
+	}
+	code, output := runTestScript(t, dir, scripts, "t1", "outer")
+	if expected := "one|two words\n" + dir + "/sub\n" + dir + "\nback outer\n"; code != 0 || output != expected {
+		t.Errorf("t1 = %d, %q, expected 0, %q", code, output, expected)
+	}
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+	code, output = runTestScript(t, dir, scripts, "t3")
+	if code != 1 || output != "start\n" {
+		t.Errorf("t3 = %d, %q, expected 1, %q", code, output, "start\n")
+	}
+	if !strings.Contains(logged.String(), "@@call cycle: t3 -> t4 -> t3") {
+		t.Errorf("expected the call chain in the error, got %q", logged.String())
+	}
+}
+
+func TestExecuteScripts(t *testing.T) {
+	t.Setenv("GREETING", "")
+	t.Setenv("REACT", "")
+	t.Setenv("HOST", "")
+	tests := map[string]struct {
+		script []string
+		code   int
+		output string
+	}{
+		"try caught": {[]string{
+			"@@try", "echo try", "sh -c 'exit 7'", "echo not reached",
+			"@@catch", "echo \"caught $NRUN_LAST_EXIT: $NRUN_ERROR\"",
+			"@@finally", "echo finally", "@@endtry",
+			"echo \"after $NRUN_LAST_EXIT [$NRUN_ERROR]\"",
+		}, 0, "try\ncaught 7: exit status 7\nfinally\nafter 0 []\n"},
+		"try uncaught": {[]string{"@@try", "sh -c 'exit 4'", "@@finally", "echo finally", "@@endtry", "echo not reached"}, 4, "finally\n"},
+		// An error in @@finally replaces the error from @@catch
+		"try finally fails": {[]string{"@@try", "false", "@@catch", "sh -c 'exit 3'", "@@finally", "echo finally", "sh -c 'exit 5'", "@@endtry"}, 5, "finally\n"},
+		"try break": {[]string{
+			"@@foreach item in a, b", "@@try", "echo {{item}}", "@@break",
+			"@@finally", "echo finally {{item}}", "@@endtry", "@@endforeach", "echo done",
+		}, 0, "a\nfinally a\ndone\n"},
+		"try stopped": {[]string{"@@try", "@@hasfile:missing", "echo not reached", "@@finally", "echo finally", "@@end", "echo not reached"}, 0, "finally\n"},
+		// SIGTERM is passed on to the running command, the script stops but @@finally is still run
+		"try terminated": {[]string{
+			"@@try", "echo start", "kill -TERM $PPID; sleep 2", "echo not reached",
+			"@@catch", "echo not caught", "@@finally", "echo finally", "@@endtry", "echo not reached",
+		}, 143, "start\nfinally\n"},
+		// SIGINT reaches the commands from the terminal, the script stops when the command is done
+		"try interrupted": {[]string{
+			"@@try", "kill -INT $PPID; sleep 0.1; echo command done", "echo not reached",
+			"@@finally", "echo finally", "@@endtry", "echo not reached",
+		}, 130, "command done\nfinally\n"},
+		"ignored lines":    {[]string{"- sh -c 'exit 3'", "echo \"last $NRUN_LAST_EXIT\"", "- false", "true", "echo \"last $NRUN_LAST_EXIT\"", "sh -c 'exit 2'", "echo not reached"}, 2, "last 3\nlast 0\n"},
+		"capture text":     {[]string{"@@capture GREETING: echo '  hello  '", "echo \"[$GREETING]\"", "@@echo: {{GREETING}}"}, 0, "[hello]\nhello\n"},
+		"capture json":     {[]string{"@@capture REACT dependencies.react: echo '{\"dependencies\":{\"react\":\"18\"}}'", "echo $REACT"}, 0, "18\n"},
+		"capture colon":    {[]string{"@@capture HOST [\"host:port\"]: echo '{\"host:port\":\"localhost:3000\"}'", "echo $HOST"}, 0, "localhost:3000\n"},
+		"capture failed":   {[]string{"echo before", "@@capture NAME: echo partial; exit 7", "echo not reached"}, 7, "before\n"},
+		"capture missing":  {[]string{"@@capture NAME version: echo '{}'", "echo not reached"}, 1, ""},
+		"capture not json": {[]string{"@@capture NAME version: echo 1.2.0", "echo not reached"}, 1, ""},
+	}
+	for name, test := range tests {
+		scripts := map[string][]string{name: test.script}
+		if code, output := runTestScript(t, t.TempDir(), scripts, name); code != test.code || output != test.output {
+			t.Errorf("%s = %d, %q, expected %d, %q", name, code, output, test.code, test.output)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package helper
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// interruptedError is returned when the script got SIGINT or SIGTERM. The exit code is 128 plus the signal number,
+// like in a shell.
+type interruptedError struct {
+	signal os.Signal
+}
+
+func (e *interruptedError) Error() string {
+	if e.signal == syscall.SIGTERM {
+		return "stopped by SIGTERM"
+	}
+	return "stopped by SIGINT"
+}
+
+func (e *interruptedError) exitCode() int {
+	if signal, ok := e.signal.(syscall.Signal); ok {
+		return 128 + int(signal)
+	}
+	return 1
+}
+
+// scriptInterrupt catches SIGINT and SIGTERM while a script runs, so the script can stop at the next line and still
+// run its @@finally parts. SIGTERM is passed on to the running commands, SIGINT already reaches them from the
+// terminal.
+type scriptInterrupt struct {
+	mutex       sync.Mutex
+	signal      os.Signal
+	interrupted chan bool
+	processes   map[*os.Process]bool
+	signals     chan os.Signal
+}
+
+func watchScriptSignals() *scriptInterrupt {
+	i := &scriptInterrupt{interrupted: make(chan bool), processes: map[*os.Process]bool{}, signals: make(chan os.Signal, 2)}
+	signal.Notify(i.signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for received := range i.signals {
+			i.mutex.Lock()
+			if i.signal == nil {
+				close(i.interrupted)
+			}
+			i.signal = received
+			if received == syscall.SIGTERM {
+				for process := range i.processes {
+					process.Signal(received)
+				}
+			}
+			i.mutex.Unlock()
+		}
+	}()
+	return i
+}
+
+// stop restores the default handling of the signals.
+func (i *scriptInterrupt) stop() {
+	signal.Stop(i.signals)
+	close(i.signals)
+}
+
+// run runs the command and keeps track of it while it runs, so a signal can be passed on to it.
+func (i *scriptInterrupt) run(cmd *exec.Cmd) error {
+	if i == nil {
+		return cmd.Run()
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	i.mutex.Lock()
+	i.processes[cmd.Process] = true
+	i.mutex.Unlock()
+	err := cmd.Wait()
+	i.mutex.Lock()
+	delete(i.processes, cmd.Process)
+	i.mutex.Unlock()
+	return err
+}
+
+// err returns an interruptedError if a signal was received.
+func (i *scriptInterrupt) err() error {
+	if i == nil {
+		return nil
+	}
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if i.signal == nil {
+		return nil
+	}
+	return &interruptedError{i.signal}
+}
+
+// pause forgets the received signal while @@finally runs, a new signal stops @@finally as well. resume brings
+// the signal back unless a new one was received.
+func (i *scriptInterrupt) pause() os.Signal {
+	if i == nil {
+		return nil
+	}
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	received := i.signal
+	if received != nil {
+		i.signal = nil
+		i.interrupted = make(chan bool)
+	}
+	return received
+}
+
+func (i *scriptInterrupt) resume(received os.Signal) {
+	if i == nil || received == nil {
+		return
+	}
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if i.signal == nil {
+		i.signal = received
+		close(i.interrupted)
+	}
+}
+
+// done returns a channel that is closed when a signal is received.
+func (i *scriptInterrupt) done() <-chan bool {
+	if i == nil {
+		return nil
+	}
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return i.interrupted
+}
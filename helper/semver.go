@@ -0,0 +1,343 @@
+package helper
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type SemVer struct {
+	Major      int64
+	Minor      int64
+	Patch      int64
+	Prerelease []string
+	Build      []string
+}
+
+type semVerComparator struct {
+	Operator string
+	Version  SemVer
+}
+
+// A SemVerRange is a list of comparator sets joined by "||". A version satisfies the range
+// when it satisfies every comparator in at least one of the sets.
+type SemVerRange [][]semVerComparator
+
+var semVerRegexp = regexp.MustCompile(`^[vV=\s]*([0-9]+)(?:\.([0-9]+))?(?:\.([0-9]+))?(?:-([0-9A-Za-z.-]+))?(?:\+([0-9A-Za-z.-]+))?$`)
+var semVerPartialRegexp = regexp.MustCompile(`^[vV=\s]*([0-9]+|[xX*])(?:\.([0-9]+|[xX*]))?(?:\.([0-9]+|[xX*]))?(?:-([0-9A-Za-z.-]+))?(?:\+([0-9A-Za-z.-]+))?$`)
+
+// ParseSemVer parses a version like "1.2.3", "v1.2.3-beta.1+build.5" or the shorter "v18" and "18.2"
+// where missing components are treated as zero.
+func ParseSemVer(version string) (SemVer, error) {
+	parts := semVerRegexp.FindStringSubmatch(strings.TrimSpace(version))
+	if parts == nil {
+		return SemVer{}, errors.New("invalid version \"" + version + "\"")
+	}
+	semVer := SemVer{}
+	semVer.Major, _ = strconv.ParseInt(parts[1], 10, 64)
+	if parts[2] != "" {
+		semVer.Minor, _ = strconv.ParseInt(parts[2], 10, 64)
+	}
+	if parts[3] != "" {
+		semVer.Patch, _ = strconv.ParseInt(parts[3], 10, 64)
+	}
+	if parts[4] != "" {
+		semVer.Prerelease = strings.Split(parts[4], ".")
+	}
+	if parts[5] != "" {
+		semVer.Build = strings.Split(parts[5], ".")
+	}
+	return semVer, nil
+}
+
+func (v SemVer) String() string {
+	version := strconv.FormatInt(v.Major, 10) + "." + strconv.FormatInt(v.Minor, 10) + "." + strconv.FormatInt(v.Patch, 10)
+	if len(v.Prerelease) > 0 {
+		version += "-" + strings.Join(v.Prerelease, ".")
+	}
+	if len(v.Build) > 0 {
+		version += "+" + strings.Join(v.Build, ".")
+	}
+	return version
+}
+
+// Compare returns -1, 0 or 1. Build metadata is ignored and a prerelease sorts before its release.
+func (v SemVer) Compare(other SemVer) int {
+	if result := compareInt(v.Major, other.Major); result != 0 {
+		return result
+	}
+	if result := compareInt(v.Minor, other.Minor); result != 0 {
+		return result
+	}
+	if result := compareInt(v.Patch, other.Patch); result != 0 {
+		return result
+	}
+	if len(v.Prerelease) == 0 && len(other.Prerelease) == 0 {
+		return 0
+	}
+	if len(v.Prerelease) == 0 {
+		return 1
+	}
+	if len(other.Prerelease) == 0 {
+		return -1
+	}
+	for i := 0; i < len(v.Prerelease) && i < len(other.Prerelease); i++ {
+		if result := comparePrereleaseIdentifier(v.Prerelease[i], other.Prerelease[i]); result != 0 {
+			return result
+		}
+	}
+	return compareInt(int64(len(v.Prerelease)), int64(len(other.Prerelease)))
+}
+
+func compareInt(a, b int64) int {
+	if a > b {
+		return 1
+	}
+	if a < b {
+		return -1
+	}
+	return 0
+}
+
+func comparePrereleaseIdentifier(a, b string) int {
+	aInt, aErr := strconv.ParseInt(a, 10, 64)
+	bInt, bErr := strconv.ParseInt(b, 10, 64)
+	if aErr == nil && bErr == nil {
+		return compareInt(aInt, bInt)
+	}
+	// Numeric identifiers always have lower precedence than alphanumeric ones
+	if aErr == nil {
+		return -1
+	}
+	if bErr == nil {
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// ParseSemVerRange parses npm range syntax: comparators (>=1.2.3 <2), caret (^1.2), tilde (~1.2.3),
+// x-ranges (1.x, 1.2.*, *), hyphen ranges (1.2 - 2.3.4) and alternatives joined by "||".
+func ParseSemVerRange(versionRange string) (SemVerRange, error) {
+	semVerRange := SemVerRange{}
+	for _, alternative := range strings.Split(versionRange, "||") {
+		comparators, err := parseComparatorSet(alternative)
+		if err != nil {
+			return nil, err
+		}
+		semVerRange = append(semVerRange, comparators)
+	}
+	return semVerRange, nil
+}
+
+func parseComparatorSet(set string) ([]semVerComparator, error) {
+	set = strings.TrimSpace(set)
+	comparators := []semVerComparator{}
+	fields := strings.Fields(set)
+	if len(fields) == 3 && fields[1] == "-" {
+		lower, err := parsePartial(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		upper, err := parsePartial(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		comparators = append(comparators, semVerComparator{">=", lower.floor()})
+		if upper.missing == 3 {
+			return comparators, nil
+		}
+		if upper.missing > 0 {
+			return append(comparators, semVerComparator{"<", upper.next()}), nil
+		}
+		return append(comparators, semVerComparator{"<=", upper.version}), nil
+	}
+	// Allow a space between the operator and the version, e.g. ">= 1.2.3"
+	joined := []string{}
+	for i := 0; i < len(fields); i++ {
+		if strings.Trim(fields[i], "<>=~^") == "" && i+1 < len(fields) {
+			joined = append(joined, fields[i]+fields[i+1])
+			i++
+		} else {
+			joined = append(joined, fields[i])
+		}
+	}
+	for _, field := range joined {
+		parsed, err := parseComparator(field)
+		if err != nil {
+			return nil, err
+		}
+		comparators = append(comparators, parsed...)
+	}
+	return comparators, nil
+}
+
+type partialVersion struct {
+	version SemVer
+	// Number of trailing components that were missing or wildcards (0-3)
+	missing int
+}
+
+func parsePartial(version string) (partialVersion, error) {
+	parts := semVerPartialRegexp.FindStringSubmatch(strings.TrimSpace(version))
+	if parts == nil {
+		return partialVersion{}, errors.New("invalid version \"" + version + "\"")
+	}
+	partial := partialVersion{}
+	numbers := []*int64{&partial.version.Major, &partial.version.Minor, &partial.version.Patch}
+	for i := 0; i < 3; i++ {
+		if parts[i+1] == "" || strings.ContainsAny(parts[i+1], "xX*") {
+			partial.missing = 3 - i
+			break
+		}
+		*numbers[i], _ = strconv.ParseInt(parts[i+1], 10, 64)
+	}
+	if partial.missing == 0 && parts[4] != "" {
+		partial.version.Prerelease = strings.Split(parts[4], ".")
+	}
+	return partial, nil
+}
+
+func (p partialVersion) floor() SemVer {
+	return p.version
+}
+
+// next returns the first version that is outside the partial version, e.g. 1.2.x -> 1.3.0-0
+func (p partialVersion) next() SemVer {
+	switch p.missing {
+	case 1:
+		return SemVer{Major: p.version.Major, Minor: p.version.Minor + 1, Prerelease: []string{"0"}}
+	case 2:
+		return SemVer{Major: p.version.Major + 1, Prerelease: []string{"0"}}
+	}
+	return p.version
+}
+
+func parseComparator(comparator string) ([]semVerComparator, error) {
+	operator := ""
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(comparator, prefix) {
+			operator = prefix
+			break
+		}
+	}
+	partial, err := parsePartial(strings.TrimPrefix(comparator[len(operator):], "v"))
+	if err != nil {
+		return nil, err
+	}
+	version := partial.version
+	any := []semVerComparator{}
+	switch operator {
+	case "", "=":
+		if partial.missing == 3 {
+			return any, nil
+		}
+		if partial.missing > 0 {
+			return []semVerComparator{{">=", version}, {"<", partial.next()}}, nil
+		}
+		return []semVerComparator{{"=", version}}, nil
+	case "^":
+		if partial.missing == 3 {
+			return any, nil
+		}
+		upper := SemVer{Major: version.Major + 1, Prerelease: []string{"0"}}
+		if version.Major == 0 {
+			if partial.missing == 2 {
+				upper = SemVer{Major: 1, Prerelease: []string{"0"}}
+			} else if version.Minor > 0 || partial.missing == 1 {
+				upper = SemVer{Minor: version.Minor + 1, Prerelease: []string{"0"}}
+			} else {
+				upper = SemVer{Patch: version.Patch + 1, Prerelease: []string{"0"}}
+			}
+		}
+		return []semVerComparator{{">=", version}, {"<", upper}}, nil
+	case "~":
+		if partial.missing == 3 {
+			return any, nil
+		}
+		upper := SemVer{Major: version.Major, Minor: version.Minor + 1, Prerelease: []string{"0"}}
+		if partial.missing == 2 {
+			upper = SemVer{Major: version.Major + 1, Prerelease: []string{"0"}}
+		}
+		return []semVerComparator{{">=", version}, {"<", upper}}, nil
+	case ">":
+		if partial.missing == 3 {
+			return []semVerComparator{{"<", SemVer{Prerelease: []string{"0"}}}}, nil
+		}
+		if partial.missing > 0 {
+			lower := partial.next()
+			lower.Prerelease = nil
+			return []semVerComparator{{">=", lower}}, nil
+		}
+	case "<=":
+		if partial.missing == 3 {
+			return any, nil
+		}
+		if partial.missing > 0 {
+			return []semVerComparator{{"<", partial.next()}}, nil
+		}
+	case ">=", "<":
+		if partial.missing == 3 {
+			if operator == "<" {
+				return []semVerComparator{{"<", SemVer{Prerelease: []string{"0"}}}}, nil
+			}
+			return any, nil
+		}
+	}
+	return []semVerComparator{{operator, version}}, nil
+}
+
+func (c semVerComparator) test(version SemVer) bool {
+	result := version.Compare(c.Version)
+	switch c.Operator {
+	case ">=":
+		return result >= 0
+	case ">":
+		return result > 0
+	case "<=":
+		return result <= 0
+	case "<":
+		return result < 0
+	}
+	return result == 0
+}
+
+// Contains reports if the version is within the range. As in npm a prerelease version only
+// matches when a comparator in the same set has a prerelease on the same major.minor.patch.
+func (r SemVerRange) Contains(version SemVer) bool {
+	for _, set := range r {
+		matches := true
+		for _, comparator := range set {
+			if !comparator.test(version) {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+		if len(version.Prerelease) == 0 {
+			return true
+		}
+		for _, comparator := range set {
+			if len(comparator.Version.Prerelease) > 0 && comparator.Version.Major == version.Major &&
+				comparator.Version.Minor == version.Minor && comparator.Version.Patch == version.Patch {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SemVerSatisfies reports if the version satisfies the npm style range.
+func SemVerSatisfies(version string, versionRange string) (bool, error) {
+	semVer, err := ParseSemVer(version)
+	if err != nil {
+		return false, err
+	}
+	semVerRange, err := ParseSemVerRange(versionRange)
+	if err != nil {
+		return false, err
+	}
+	return semVerRange.Contains(semVer), nil
+}
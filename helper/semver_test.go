@@ -0,0 +1,95 @@
+package helper
+
+import "testing"
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		v1       string
+		v2       string
+		expected int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"v18", "18.0.0", 0},
+		{"1.2.4", "1.2.3", 1},
+		{"1.10.0", "1.9.9", 1},
+		{"1.2.3-beta", "1.2.3", -1},
+		{"1.2.3-alpha", "1.2.3-alpha.1", -1},
+		{"1.2.3-alpha.1", "1.2.3-alpha.beta", -1},
+		{"1.2.3-beta.2", "1.2.3-beta.11", -1},
+		{"1.2.3-rc.1", "1.2.3-beta.11", 1},
+		{"1.2.3+build.1", "1.2.3+build.2", 0},
+		{"1.2.x", "1.2.3", -2},
+		{"latest", "1.2.3", -2},
+	}
+	for _, test := range tests {
+		if result := VersionCompare(test.v1, test.v2); result != test.expected {
+			t.Errorf("VersionCompare(%q, %q) = %d, expected %d", test.v1, test.v2, result, test.expected)
+		}
+	}
+}
+
+func TestSemVerSatisfies(t *testing.T) {
+	tests := []struct {
+		version  string
+		rng      string
+		expected bool
+	}{
+		{"1.2.3", "1.2.3", true},
+		{"1.2.4", "1.2.3", false},
+		{"18.17.1", ">=18", true},
+		{"16.20.0", ">=18", false},
+		{"18.17.1", ">=14 <19", true},
+		{"19.0.0", ">=14 <19", false},
+		{"1.9.9", "^1.2.3", true},
+		{"2.0.0", "^1.2.3", false},
+		{"1.2.2", "^1.2.3", false},
+		{"0.2.9", "^0.2.3", true},
+		{"0.3.0", "^0.2.3", false},
+		{"0.0.3", "^0.0.3", true},
+		{"0.0.4", "^0.0.3", false},
+		{"1.2.9", "~1.2.3", true},
+		{"1.3.0", "~1.2.3", false},
+		{"1.9.0", "~1", true},
+		{"1.4.2", "1.x", true},
+		{"2.0.0", "1.x", false},
+		{"1.2.7", "1.2.*", true},
+		{"5.0.0", "*", true},
+		{"5.0.0", "", true},
+		{"1.5.0", "1.2.3 - 2.3.4", true},
+		{"2.3.4", "1.2.3 - 2.3.4", true},
+		{"2.3.5", "1.2.3 - 2.3.4", false},
+		{"2.3.9", "1.2 - 2.3", true},
+		{"2.4.0", "1.2 - 2.3", false},
+		{"3.0.0", "^1.0.0 || ^3.0.0", true},
+		{"2.0.0", "^1.0.0 || ^3.0.0", false},
+		{"1.3.0", ">1.2", true},
+		{"1.2.9", ">1.2", false},
+		{"1.2.9", "<=1.2", true},
+		{"1.3.0", "<=1.2", false},
+		{"1.3.0", ">= 1.2.0", true},
+		{"1.2.3-beta.2", "^1.2.3-beta.1", true},
+		{"1.3.0-beta.1", "^1.2.3-beta.1", false},
+		{"2.0.0-rc.1", "^1.0.0", false},
+		{"1.2.3-rc.1", "*", false},
+		{"v20.5.0", ">=18", true},
+	}
+	for _, test := range tests {
+		result, err := SemVerSatisfies(test.version, test.rng)
+		if err != nil {
+			t.Errorf("SemVerSatisfies(%q, %q) returned error %v", test.version, test.rng, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("SemVerSatisfies(%q, %q) = %v, expected %v", test.version, test.rng, result, test.expected)
+		}
+	}
+}
+
+func TestParseSemVerRangeInvalid(t *testing.T) {
+	for _, rng := range []string{">=abc", "^1.2.3.4", "1.2.3 -"} {
+		if _, err := ParseSemVerRange(rng); err == nil {
+			t.Errorf("ParseSemVerRange(%q) should have returned an error", rng)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package helper
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/shlex"
+)
+
+const shellInitBash = `# nrun shell integration, add this to ~/.bashrc:
+#   eval "$(nrun --shell-init bash)"
+ncd() {
+	local dir
+	if [ $# -eq 0 ]; then
+		dir="${NRUN_SHELL_PROJECT_PATH:-}"
+	else
+		dir="$(command nrun -path "$1")"
+	fi
+	[ -n "$dir" ] && cd "$dir"
+}
+_nrun_ncd_complete() {
+	COMPREPLY=($(compgen -W "$(command nrun -pl 2>/dev/null | sed -n '2,$s/ *:.*//p')" -- "${COMP_WORDS[COMP_CWORD]}"))
+}
+complete -F _nrun_ncd_complete ncd
+nrun_prompt() {
+	[ -n "${NRUN_SHELL_PROJECT:-}" ] && printf '[%s] ' "$NRUN_SHELL_PROJECT"
+}
+_nrun_hook() {
+	[ "$PWD" = "${_NRUN_LAST_PWD:-}" ] && return
+	_NRUN_LAST_PWD="$PWD"
+	local out
+	out="$(command nrun --shell-hook bash{{flags}} 2>/dev/null)" && eval "$out"
+}
+case ";${PROMPT_COMMAND:-};" in
+	*";_nrun_hook;"*) ;;
+	*) PROMPT_COMMAND="_nrun_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+_nrun_hook
+`
+
+const shellInitZsh = `# nrun shell integration, add this to ~/.zshrc:
+#   eval "$(nrun --shell-init zsh)"
+ncd() {
+	local dir
+	if [ $# -eq 0 ]; then
+		dir="${NRUN_SHELL_PROJECT_PATH:-}"
+	else
+		dir="$(command nrun -path "$1")"
+	fi
+	[ -n "$dir" ] && cd "$dir"
+}
+_nrun_ncd() {
+	local -a projects
+	projects=(${(f)"$(command nrun -pl 2>/dev/null | sed -n '2,$s/ *:.*//p')"})
+	compadd -a projects
+}
+(( $+functions[compdef] )) && compdef _nrun_ncd ncd
+nrun_prompt() {
+	[ -n "${NRUN_SHELL_PROJECT:-}" ] && printf '[%s] ' "$NRUN_SHELL_PROJECT"
+}
+_nrun_hook() {
+	local out
+	out="$(command nrun --shell-hook zsh{{flags}} 2>/dev/null)" && eval "$out"
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd _nrun_hook
+_nrun_hook
+`
+
+const shellInitFish = `# nrun shell integration, add this to ~/.config/fish/config.fish:
+#   nrun --shell-init fish | source
+function ncd
+	set -l dir $NRUN_SHELL_PROJECT_PATH
+	if test (count $argv) -gt 0
+		set dir (command nrun -path $argv[1])
+	end
+	test -n "$dir"; and cd $dir
+end
+complete -c ncd -f -a '(command nrun -pl 2>/dev/null | sed -n "2,\$s/ *:.*//p")'
+function nrun_prompt
+	test -n "$NRUN_SHELL_PROJECT"; and printf '[%s] ' $NRUN_SHELL_PROJECT
+end
+function _nrun_hook --on-variable PWD
+	set -l out (command nrun --shell-hook fish{{flags}} 2>/dev/null); and printf '%s\n' $out | source
+end
+_nrun_hook
+`
+
+// ShellInit prints the functions for the shell: ncd to jump to a project, nrun_prompt for the prompt and a
+// hook that keeps track of the current project when the directory changes.
+func ShellInit(shell string, flagList *FlagList) error {
+	scripts := map[string]string{"bash": shellInitBash, "zsh": shellInitZsh, "fish": shellInitFish}
+	script, ok := scripts[shell]
+	if !ok {
+		return errors.New("usage: nrun --shell-init bash|zsh|fish [--auto-env]")
+	}
+	flags := ""
+	if flagList.ShellAutoEnv != nil && *flagList.ShellAutoEnv {
+		flags = " --auto-env"
+	}
+	fmt.Print(strings.ReplaceAll(script, "{{flags}}", flags))
+	return nil
+}
+
+// GetCurrentProject returns the registered project that contains the path, the deepest one if projects are nested.
+func GetCurrentProject(path string, projects map[string]string) (string, string) {
+	path = cleanConfigPath(path)
+	name, root := "", ""
+	for projectName, projectPath := range projects {
+		projectPath = cleanConfigPath(projectPath)
+		if len(projectPath) == 0 || len(projectPath) < len(root) {
+			continue
+		}
+		if path == projectPath || strings.HasPrefix(path, strings.TrimRight(projectPath, "/")+"/") {
+			if len(projectPath) > len(root) || projectName < name {
+				name, root = projectName, projectPath
+			}
+		}
+	}
+	return name, root
+}
+
+// readDotEnv reads KEY=value lines from a .env file. Comments, empty lines and an "export " prefix are ignored
+// and values can be quoted.
+func readDotEnv(file string) map[string]string {
+	values := make(map[string]string)
+	handle, err := os.Open(file)
+	if err != nil {
+		return values
+	}
+	defer handle.Close()
+	scanner := bufio.NewScanner(handle)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || len(key) == 0 {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) > 1 && (value[0] == '"' || value[0] == '\'') {
+			if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+				value = value[1 : end+1]
+			}
+		} else if index := strings.Index(value, " #"); index >= 0 {
+			value = strings.TrimSpace(value[:index])
+		}
+		values[key] = value
+	}
+	return values
+}
+
+// getProjectShellEnv returns the values from the .env file in the project and the env that nrun uses for
+// every script in it (the "*" script in "env"). The values from nrun win.
+func getProjectShellEnv(root string) map[string]string {
+	values := readDotEnv(root + "/.env")
+	_, envs, _, _, vars, _, _ := GetDefaultValues(root)
+	if len(envs["*"]) > 0 {
+		if env, err := NewTemplateContext(vars).Render(envs["*"]); err == nil {
+			parts, _ := shlex.Split(env)
+			for _, part := range parts {
+				if key, value, found := strings.Cut(part, "="); found && len(key) > 0 {
+					values[key] = value
+				}
+			}
+		}
+	}
+	return values
+}
+
+type shellWriter struct {
+	fish  bool
+	lines []string
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func fishQuote(value string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(value, `\`, `\\`), "'", `\'`) + "'"
+}
+
+func (w *shellWriter) set(key string, value string) {
+	if w.fish {
+		w.lines = append(w.lines, "set -gx "+key+" "+fishQuote(value))
+	} else {
+		w.lines = append(w.lines, "export "+key+"="+shellQuote(value))
+	}
+}
+
+func (w *shellWriter) unset(key string) {
+	if w.fish {
+		w.lines = append(w.lines, "set -e "+key)
+	} else {
+		w.lines = append(w.lines, "unset "+key)
+	}
+}
+
+// ShellHook is called by the shell when the directory changes and prints the commands that update
+// NRUN_SHELL_PROJECT. With --auto-env the env of the project is loaded when entering it and removed when
+// leaving it. The loaded keys are remembered in NRUN_SHELL_ENV_KEYS and the values they replaced in
+// _NRUN_OLD_<key>.
+func ShellHook(shell string, flagList *FlagList) error {
+	if shell != "bash" && shell != "zsh" && shell != "fish" {
+		return errors.New("usage: nrun --shell-hook bash|zsh|fish [--auto-env]")
+	}
+	config, err := GetEffectiveConfig("")
+	if err != nil {
+		return err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	name, root := GetCurrentProject(cwd, config.Projects)
+	writer := &shellWriter{fish: shell == "fish"}
+	if len(name) > 0 {
+		writer.set("NRUN_SHELL_PROJECT", name)
+		writer.set("NRUN_SHELL_PROJECT_PATH", root)
+	} else if len(os.Getenv("NRUN_SHELL_PROJECT")) > 0 {
+		writer.unset("NRUN_SHELL_PROJECT")
+		writer.unset("NRUN_SHELL_PROJECT_PATH")
+	}
+
+	if flagList.ShellAutoEnv != nil && *flagList.ShellAutoEnv && os.Getenv("NRUN_SHELL_ENV_DIR") != root {
+		// The values the variables had before the project was entered are restored and the variables that
+		// didn't exist are removed
+		restored := map[string]string{}
+		removed := map[string]bool{}
+		for _, key := range strings.Fields(os.Getenv("NRUN_SHELL_ENV_KEYS")) {
+			if !identifierRegexp.MatchString(key) {
+				continue
+			}
+			if value, found := os.LookupEnv("_NRUN_OLD_" + key); found {
+				writer.set(key, value)
+				writer.unset("_NRUN_OLD_" + key)
+				restored[key] = value
+			} else {
+				writer.unset(key)
+				removed[key] = true
+			}
+		}
+		keys := []string{}
+		if len(root) > 0 {
+			values := getProjectShellEnv(root)
+			for key := range values {
+				// The output is evaluated by the shell, so anything that isn't a plain name is left out
+				if identifierRegexp.MatchString(key) {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				previous, found := restored[key]
+				if !found && !removed[key] {
+					previous, found = os.LookupEnv(key)
+				}
+				if found {
+					writer.set("_NRUN_OLD_"+key, previous)
+				}
+				writer.set(key, values[key])
+			}
+		}
+		if len(keys) > 0 {
+			writer.set("NRUN_SHELL_ENV_DIR", root)
+			writer.set("NRUN_SHELL_ENV_KEYS", strings.Join(keys, " "))
+		} else {
+			writer.unset("NRUN_SHELL_ENV_DIR")
+			writer.unset("NRUN_SHELL_ENV_KEYS")
+		}
+	}
+	for _, line := range writer.lines {
+		fmt.Println(line)
+	}
+	return nil
+}
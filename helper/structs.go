@@ -11,21 +11,49 @@ type PackageJSON struct {
 	Dependencies    map[string]string      `json:"dependencies"`
 	Nyc             map[string]interface{} `json:"nyc"`
 	DevDependencies map[string]string      `json:"devDependencies"`
+	Engines         map[string]string      `json:"engines"`
+	Workspaces      interface{}            `json:"workspaces"`
 }
 
 type Config struct {
-	Env                 map[string]map[string]string    `json:"env"`
-	Path                map[string]map[string]string    `json:"path"`
-	Pipes               map[string]map[string][]string  `json:"pipes"`
-	Vars                map[string]string               `json:"vars"`
-	Projects            map[string]string               `json:"projects"`
-	Alias               map[string]string               `json:"alias"`
-	Scripts             map[string][]string             `json:"scripts"`
-	WebGetTemplates     map[string]WebGetTemplateStruct `json:"webget"`
-	XAuthTokens         map[string]string               `json:"xauthtokens"`
-	PersonalFlags       map[string][]string             `json:"personalflags"`
-	TokenTemplates      map[string]string               `json:"tokentemplates"`
-	PackageJSONOverride map[string]interface{}          `json:"package.json"`
+	Version             int                             `json:"version,omitempty"`
+	Env                 map[string]map[string]string    `json:"env,omitempty"`
+	Path                map[string]map[string]string    `json:"path,omitempty"`
+	Pipes               map[string]map[string][]string  `json:"pipes,omitempty"`
+	Vars                map[string]string               `json:"vars,omitempty"`
+	Projects            map[string]string               `json:"projects,omitempty"`
+	Alias               map[string]string               `json:"alias,omitempty"`
+	Scripts             map[string][]string             `json:"scripts,omitempty"`
+	WebGetTemplates     map[string]WebGetTemplateStruct `json:"webget,omitempty"`
+	XAuthTokens         map[string]string               `json:"xauthtokens,omitempty"`
+	PersonalFlags       map[string][]string             `json:"personalflags,omitempty"`
+	TokenTemplates      map[string]string               `json:"tokentemplates,omitempty"`
+	PackageJSONOverride map[string]interface{}          `json:"package.json,omitempty"`
+	Requires            []string                        `json:"requires,omitempty"`
+	EngineCheck         string                          `json:"enginecheck,omitempty"`
+	Include             []string                        `json:"include,omitempty"`
+	Profiles            map[string]ProfileStruct        `json:"profiles,omitempty"`
+	// The projects given as objects, see configprojects.go
+	ProjectDetails map[string]ProjectStruct `json:"-"`
+}
+
+type ProjectStruct struct {
+	Path           string            `json:"path"`
+	Description    string            `json:"description,omitempty"`
+	Tags           []string          `json:"tags,omitempty"`
+	Script         string            `json:"script,omitempty"`
+	Env            map[string]string `json:"env,omitempty"`
+	Vars           map[string]string `json:"vars,omitempty"`
+	PackageManager string            `json:"packagemanager,omitempty"`
+}
+
+type ProfileStruct struct {
+	Description string                       `json:"description,omitempty"`
+	Env         map[string]map[string]string `json:"env,omitempty"`
+	Vars        map[string]string            `json:"vars,omitempty"`
+	XAuthTokens map[string]string            `json:"xauthtokens,omitempty"`
+	BaseURLs    map[string]string            `json:"baseurls,omitempty"`
+	Protected   bool                         `json:"protected,omitempty"`
 }
 
 type WebGetTemplateStruct struct {
@@ -54,6 +82,15 @@ type FlagList struct {
 	OriginalPath             string
 	ShowCurrentProjectInfo   *bool
 	AddProject               *bool
+	ProjectStatus            *bool
+	JSONOutput               *bool
+	Discover                 *string
+	ProjectCommand           *bool
+	ShellInit                *string
+	ShellHook                *string
+	ShellAutoEnv             *bool
+	DiscoverDepth            *int
+	DiscoverGit              *bool
 	RemoveProject            *bool
 	GetProjectPath           *bool
 	ListProjects             *bool
@@ -93,6 +130,18 @@ type FlagList struct {
 	NoPipes                  *bool
 	ForcePipes               *bool
 	Sleep                    *int64
+	DependencyCheck          *bool
+	ConfigCheck              *bool
+	ConfigShow               *bool
+	ConfigCommand            *bool
+	ConfigLocal              *bool
+	ConfigGlobal             *bool
+	ConfigType               *string
+	ConfigRestore            *bool
+	ConfigMigrate            *bool
+	Profile                  *string
+	AssumeYes                *bool
+	Answers                  map[string]string
 }
 
 type Memory struct {
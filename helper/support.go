@@ -8,8 +8,6 @@ import (
 	"log"
 	"os"
 	"os/exec"
-	"os/user"
-	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
@@ -53,196 +51,111 @@ func GetDefaultValues(path string) (map[string]string, map[string]string, map[st
 	packageJson := make(map[string]interface{}, 1000)
 	pipes := make(map[string][]string, 1000)
 
-	usr, _ := user.Current()
-	dir := usr.HomeDir
-	config, err := ReadConfig(dir + "/.nrun.json")
+	// The global config files and every local .nrun.json from the repository root down to the
+	// current directory are merged, see GetEffectiveConfig
+	config, err := GetEffectiveConfig(path)
 	if err != nil {
-		if err.Error() != "config file not found" {
-			log.Println("Failed global .nrun.json with", err)
-		}
-	} else {
-		for k, v := range config.Path["*"] {
-			defaults[k] = v
-		}
-		for k, v := range config.Path[path] {
-			defaults[k] = v
-		}
-		for k, v := range config.Env["*"] {
-			defaultEnvs[k] = v
-		}
-		for k, v := range config.Env[path] {
-			defaultEnvs[k] = v
-		}
-		for k, v := range config.Vars {
+		log.Println("Failed reading config with", err)
+		return defaults, defaultEnvs, projects, scripts, vars, packageJson, pipes
+	}
+	for k, v := range config.Vars {
+		vars[k] = v
+	}
+	// The vars of the project in the path replace the global vars
+	if name := getProjectAtPath(path, config.Projects); len(name) > 0 {
+		for k, v := range config.ProjectDetails[name].Vars {
 			vars[k] = v
 		}
-		for k, v := range config.Projects {
-			projects[k] = v
-		}
-		for k, v := range config.Scripts {
-			scripts[k] = v
-		}
-		for k, v := range config.Pipes {
-			klist := strings.Split(k, ",")
-			for _, k2 := range klist {
-				k2 = strings.TrimSpace(k)
-				if k2[0] == '@' && len(config.Projects[k2[1:]]) > 0 && config.Projects[k2[1:]] == path {
-					for k3, v2 := range v {
-						pipes[k3] = v2
-					}
-				} else if k2 == "*" {
-					for k3, v2 := range v {
-						pipes[k3] = v2
-					}
-				} else if k2 == path {
-					for k3, v2 := range v {
-						pipes[k3] = v2
-					}
-				}
-			}
-		}
-		if config.PackageJSONOverride != nil {
-			if packageJson["scripts"] == nil {
-				packageJson["scripts"] = make(map[string]interface{}, 1000)
-			}
-			if config.PackageJSONOverride["*"] != nil {
-				for k, v := range config.PackageJSONOverride["*"].(map[string]interface{}) {
-					vType := reflect.TypeOf(v)
-					if vType.Kind() == reflect.Map {
-						for k2, v2 := range v.(map[string]interface{}) {
-							if k == "scripts" {
-								packageJson["scripts"].(map[string]interface{})[k2] = v2
-							}
-						}
-					}
-				}
-			}
-			for kList, v := range config.PackageJSONOverride {
-				kList := strings.Split(kList, ",")
-				for _, k := range kList {
-					k = strings.TrimSpace(k)
-					if path == k {
-						vType := reflect.TypeOf(v)
-						if vType.Kind() == reflect.Map {
-							for k2, v2 := range v.(map[string]interface{}) {
-								for k3, v3 := range v2.(map[string]interface{}) {
-									if k2 == "scripts" {
-										packageJson["scripts"].(map[string]interface{})[k3] = v3
-									}
-								}
-							}
-						}
-					}
-					if len(k) > 1 && k[0] == '@' && len(config.Projects[k[1:]]) > 0 && config.Projects[k[1:]] == path {
-						vType := reflect.TypeOf(v)
-						if vType.Kind() == reflect.Map {
-							for k2, v2 := range v.(map[string]interface{}) {
-								for k3, v3 := range v2.(map[string]interface{}) {
-									if k2 == "scripts" {
-										packageJson["scripts"].(map[string]interface{})[k3] = v3
-									}
-								}
-							}
-						}
-					}
-				}
-			}
-		}
 	}
-
-	if path == "" {
-		return defaults, defaultEnvs, projects, scripts, vars, packageJson, pipes
+	for k, v := range config.Projects {
+		projects[k] = v
 	}
-	config, err = ReadConfig("./.nrun.json")
-	if err != nil {
-		if err.Error() != "config file not found" {
-			log.Println("Failed local .nrun.json with", err)
-		}
-	} else {
-		for k, v := range config.Path["*"] {
-			defaults[k] = v
-		}
-		for k, v := range config.Path[path] {
+	for k, v := range config.Scripts {
+		scripts[k] = v
+	}
+
+	// Keys in the path keyed sections can be exact paths, @project, tag:name, globs or *. Values from the more
+	// specific keys are applied last, see MatchingPathKeys
+	forMatchingKeys(config.Path, path, config, func(values map[string]string) {
+		for k, v := range values {
 			defaults[k] = v
 		}
-		for k, v := range config.Env["*"] {
-			defaultEnvs[k] = v
+	})
+	// The env of a project is used as if it was given under "@project" in "env"
+	envs := make(map[string]map[string]string, len(config.Env))
+	for k, v := range config.Env {
+		envs[k] = v
+	}
+	for name, details := range config.ProjectDetails {
+		if len(details.Env) > 0 {
+			projectEnv := make(map[string]string, len(details.Env))
+			for k, v := range config.Env["@"+name] {
+				projectEnv[k] = v
+			}
+			for k, v := range details.Env {
+				projectEnv[k] = v
+			}
+			envs["@"+name] = projectEnv
 		}
-		for k, v := range config.Env[path] {
+	}
+	forMatchingKeys(envs, path, config, func(values map[string]string) {
+		for k, v := range values {
 			defaultEnvs[k] = v
 		}
-		for k, v := range config.Vars {
-			vars[k] = v
-		}
-		for k, v := range config.Pipes {
-			klist := strings.Split(k, ",")
-			for _, k2 := range klist {
-				k2 = strings.TrimSpace(k)
-				if k2[0] == '@' && len(config.Projects[k2[1:]]) > 0 && config.Projects[k2[1:]] == path {
-					for k3, v2 := range v {
-						pipes[k3] = v2
-					}
-				} else if k2 == "*" {
-					for k3, v2 := range v {
-						pipes[k3] = v2
-					}
-				} else if k2 == path {
-					for k3, v2 := range v {
-						pipes[k3] = v2
-					}
-				}
+	})
+	// The variables in the env of the active profile replace the ones with the same name in the env above
+	if profile, ok := config.Profiles[activeProfile]; ok && len(activeProfile) > 0 {
+		forMatchingKeys(profile.Env, path, config, func(values map[string]string) {
+			for k, v := range values {
+				defaultEnvs[k] = mergeEnv(defaultEnvs[k], v)
 			}
+		})
+	}
+	forMatchingKeys(config.Pipes, path, config, func(values map[string][]string) {
+		for k, v := range values {
+			pipes[k] = v
 		}
-		if config.PackageJSONOverride != nil {
-			if packageJson["scripts"] == nil {
-				packageJson["scripts"] = make(map[string]interface{}, 1000)
-			}
-			for kList, v := range config.PackageJSONOverride {
-				kList := strings.Split(kList, ",")
-				for _, k := range kList {
-					k = strings.TrimSpace(k)
-					if path == k {
-						vType := reflect.TypeOf(v)
-						if vType.Kind() == reflect.Map {
-							for k2, v2 := range v.(map[string]interface{}) {
-								for k3, v3 := range v2.(map[string]interface{}) {
-									if k2 == "scripts" {
-										packageJson["scripts"].(map[string]interface{})[k3] = v3
-									}
-								}
-							}
-						}
-					}
-					if len(k) > 1 && k[0] == '@' && len(config.Projects[k[1:]]) > 0 && config.Projects[k[1:]] == path {
-						vType := reflect.TypeOf(v)
-						if vType.Kind() == reflect.Map {
-							for k2, v2 := range v.(map[string]interface{}) {
-								for k3, v3 := range v2.(map[string]interface{}) {
-									if k2 == "scripts" {
-										packageJson["scripts"].(map[string]interface{})[k3] = v3
-									}
-								}
-							}
-						}
+	})
+	if config.PackageJSONOverride != nil {
+		overrides := make(map[string]interface{}, 1000)
+		packageJson["scripts"] = overrides
+		forMatchingKeys(config.PackageJSONOverride, path, config, func(value interface{}) {
+			if override, ok := value.(map[string]interface{}); ok {
+				if overrideScripts, ok := override["scripts"].(map[string]interface{}); ok {
+					for k, v := range overrideScripts {
+						overrides[k] = v
 					}
 				}
 			}
-		}
+		})
 	}
 
 	return defaults, defaultEnvs, projects, scripts, vars, packageJson, pipes
 }
 
+// forMatchingKeys calls apply with the values of the keys in a path keyed section that match the path, least
+// specific first, see MatchingPathKeys.
+func forMatchingKeys[V any](section map[string]V, path string, config *Config, apply func(V)) {
+	keys := make([]string, 0, len(section))
+	for k := range section {
+		keys = append(keys, k)
+	}
+	for _, k := range MatchingPathKeys(keys, path, config.Projects, config.ProjectDetails) {
+		apply(section[k])
+	}
+}
+
 func ApplyPackageJSONOverrides(packageJSON *PackageJSON, packageJSONOverrides map[string]interface{}) *PackageJSON {
 	for k, v := range packageJSONOverrides {
-		vType := reflect.TypeOf(v)
-		if vType.Kind() == reflect.Map {
+		if vMap, ok := v.(map[string]interface{}); ok {
 			if k == "scripts" {
 				if packageJSON.Scripts == nil {
 					packageJSON.Scripts = make(map[string]string, 1000)
 				}
-				for k2, v2 := range v.(map[string]interface{}) {
-					packageJSON.Scripts[k2] = v2.(string)
+				for k2, v2 := range vMap {
+					if script, ok := v2.(string); ok {
+						packageJSON.Scripts[k2] = script
+					}
 				}
 			}
 		}
@@ -264,6 +177,15 @@ func ParseFlags() *FlagList {
 	flagList.UseAnotherPath = flag.String("p", "", "Use another path to find the package.json")
 	flagList.ShowCurrentProjectInfo = flag.Bool("i", false, "Show current project info")
 	flagList.AddProject = flag.Bool("pa", false, "Add a project to the config")
+	flagList.ProjectStatus = flag.Bool("status", false, "Show git and health status for every project with -pl")
+	flagList.JSONOutput = flag.Bool("json", false, "Print the output as JSON (-pl)")
+	flagList.Discover = flag.String("discover", "", "Find projects below a directory and add them to the config")
+	flagList.DiscoverDepth = flag.Int("depth", 3, "How many directory levels --discover looks in")
+	flagList.DiscoverGit = flag.Bool("git", false, "Let --discover add git repositories without a package.json too")
+	flagList.ProjectCommand = flag.Bool("project", false, "Maintain the projects in the config (rename|move|prune|doctor)")
+	flagList.ShellInit = flag.String("shell-init", "", "Print the shell integration for bash, zsh or fish")
+	flagList.ShellHook = flag.String("shell-hook", "", "Used by the shell integration when the directory changes")
+	flagList.ShellAutoEnv = flag.Bool("auto-env", false, "Let the shell integration load the env of the project when entering it")
 	flagList.RemoveProject = flag.Bool("pr", false, "Remove a project from the config")
 	flagList.GetProjectPath = flag.Bool("path", false, "Get the path of a project from the config")
 	flagList.ListProjects = flag.Bool("pl", false, "List all projects from the config")
@@ -301,12 +223,23 @@ func ParseFlags() *FlagList {
 	flagList.NoPipes = flag.Bool("np", false, "Do not use pipes")
 	flagList.ForcePipes = flag.Bool("fp", false, "Force pipes")
 	flagList.Sleep = flag.Int64("sleep", 0, "Sleep for a given amount of milliseconds")
+	flagList.DependencyCheck = flag.Bool("dc", false, "Check installed dependencies against the versions in package.json")
+	flagList.ConfigCheck = flag.Bool("config-check", false, "Validate the global and local config files")
+	flagList.ConfigShow = flag.Bool("config-show", false, "Show the effective config after merging all config files")
+	flagList.ConfigCommand = flag.Bool("config", false, "Get, set, unset or list values in the config (get|set|unset|list <path> [value])")
+	flagList.Profile = flag.String("profile", "", "Use a profile from the config (can also be set with NRUN_PROFILE)")
+	flagList.AssumeYes = flag.Bool("yes", false, "Answer yes to every question, e.g. when using a protected profile")
+	flagList.Answers = make(map[string]string)
+	flag.Var(ScriptAnswers(flagList.Answers), "answer", "Answer a question of @@ask, @@choose or @@confirm in an nrun script (name=value, can be used more than once)")
+	flagList.ConfigMigrate = flag.Bool("config-migrate", false, "Upgrade the config to the current config version, the changes are shown first")
+	flagList.ConfigRestore = flag.Bool("config-restore", false, "Restore the config from a backup (list|n, where 1 is the newest backup)")
+	flagList.ConfigLocal = flag.Bool("local", false, "Use the closest local .nrun.json with --config")
+	flagList.ConfigGlobal = flag.Bool("global", false, "Use the global .nrun.json with --config")
+	flagList.ConfigType = flag.String("type", "", "Type of the value given to --config set (string, number, bool or json)")
 	// Inactive flags
 	flagList.TestAlarm = flag.Int64("t", 0, "Measure times in tests and notify when they are too long (time given in milliseconds)")
 
-	usr, _ := user.Current()
-	homeDir := usr.HomeDir
-	config, _ := ReadConfig(homeDir + "/.nrun.json")
+	config, _ := GetEffectiveConfig("")
 	if config != nil {
 		if config.PersonalFlags != nil {
 			flagList.PersonalFlags = make(map[string]*bool, len(config.PersonalFlags))
@@ -467,23 +400,15 @@ func GetShellByMagic(key string) (string, error) {
 
 var configCache = make(map[string]*Config, 100)
 
+// WriteConfig replaces the config file under a lock and keeps a backup of the old file, see UpdateConfig
+// for changing a config without losing changes made by other nrun processes.
 func WriteConfig(filename string, config *Config) error {
-	configCache[filename] = config
-	jsonFile, err := os.Create(filename)
+	unlock, err := LockConfigFile(filename)
 	if err != nil {
 		return err
-	} else {
-		jsonData, _ := json.MarshalIndent(config, "", "  ")
-		_, err = jsonFile.Write(jsonData)
-		if err != nil {
-			return err
-		}
-		err := jsonFile.Close()
-		if err != nil {
-			return err
-		}
 	}
-	return nil
+	defer unlock()
+	return writeConfig(filename, config)
 }
 
 func ReadConfig(filepath string) (*Config, error) {
@@ -493,79 +418,82 @@ func ReadConfig(filepath string) (*Config, error) {
 	if !FileExists(filepath) {
 		return nil, errors.New("config file not found")
 	}
-	file, err := os.Open(filepath)
+	byteValue, err := os.ReadFile(filepath)
 	if err != nil {
+		log.Println("Failed with", err)
 		return nil, err
 	}
-	defer file.Close()
-	if _, err := os.Stat(filepath); !errors.Is(err, os.ErrNotExist) {
-		jsonFile, err := os.Open(filepath)
-		if err != nil {
-			log.Println("Failed with", err)
-			return nil, err
-		} else {
-			byteValue, _ := os.ReadFile(jsonFile.Name())
-			var config Config
-			_ = json.Unmarshal(byteValue, &config)
-			jsonFile.Close()
-			configCache[filepath] = &config
-			return &config, nil
-		}
+	jsonData, _, problem := parseConfigData(filepath, byteValue)
+	if problem != nil {
+		return nil, errors.New(problem.String() + " (run nrun --config-check for details)")
 	}
-	return nil, err
+	var config Config
+	err = json.Unmarshal(jsonData, &config)
+	if err != nil {
+		return nil, describeConfigError(filepath, byteValue, err)
+	}
+	configCache[filepath] = &config
+	return &config, nil
 }
 
-func ProcessVarsOnString(data []byte, vars map[string]string) []byte {
-	for key, value := range vars {
-		data = []byte(strings.Replace(string(data), "{{"+key+"}}", value, -1))
+// VerifyConfigFiles returns the first error found when reading the given config files. Missing files are ignored.
+func VerifyConfigFiles(files ...string) error {
+	for _, file := range files {
+		if _, err := ReadConfig(file); err != nil && err.Error() != "config file not found" {
+			if version := readConfigVersion(file); version < CurrentConfigVersion {
+				return fmt.Errorf("Invalid config: %s (the file uses config version %d, nrun --config-migrate may fix this)", err, version)
+			}
+			return errors.New("Invalid config: " + err.Error())
+		}
 	}
-	return data
+	return nil
 }
-func ApplyVars(data map[string]string, vars map[string]string) map[string]string {
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		fmt.Println("Error:", err)
-		return data
-	}
-
-	jsonData = ProcessVarsOnString(jsonData, vars)
 
-	err = json.Unmarshal(jsonData, &data)
-	if err != nil {
-		fmt.Println("Error:", err)
-		return data
+// ApplyVars replaces the vars in every value. Placeholders that can't be resolved yet (undefined vars and
+// {{sh:...}}) are kept and dealt with when the value is used, see TemplateContext.
+func ApplyVars(data map[string]string, vars map[string]string) map[string]string {
+	context := NewTemplateContext(vars)
+	context.Lenient = true
+	for key, value := range data {
+		data[key], _ = context.Render(value)
 	}
 	return data
 }
-func ApplyVarsArray(data map[string][]string, vars map[string]string) map[string][]string {
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		fmt.Println("Error:", err)
-		return data
-	}
-
-	jsonData = ProcessVarsOnString(jsonData, vars)
 
-	err = json.Unmarshal(jsonData, &data)
-	if err != nil {
-		fmt.Println("Error:", err)
-		return data
+func ApplyVarsArray(data map[string][]string, vars map[string]string) map[string][]string {
+	context := NewTemplateContext(vars)
+	context.Lenient = true
+	for key, values := range data {
+		for index, value := range values {
+			values[index], _ = context.Render(value)
+		}
+		data[key] = values
 	}
 	return data
 }
-func ApplyVarsTemplateStruct(data WebGetTemplateStruct, vars map[string]string) WebGetTemplateStruct {
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		fmt.Println("Error:", err)
-		return data
-	}
-
-	jsonData = ProcessVarsOnString(jsonData, vars)
 
-	err = json.Unmarshal(jsonData, &data)
-	if err != nil {
-		fmt.Println("Error:", err)
-		return data
+// RenderWebGetTemplate replaces the placeholders in a webget template just before it's used.
+func RenderWebGetTemplate(template WebGetTemplateStruct, vars map[string]string) (WebGetTemplateStruct, error) {
+	context := NewTemplateContext(vars)
+	var err error
+	render := func(value string) string {
+		rendered, renderErr := context.Render(value)
+		if renderErr != nil && err == nil {
+			err = renderErr
+		}
+		return rendered
 	}
-	return data
+	template.Method = render(template.Method)
+	template.URL = render(template.URL)
+	template.Format = render(template.Format)
+	template.Body = render(template.Body)
+	template.XAuthToken = render(template.XAuthToken)
+	headers := make(map[string]string, len(template.Headers))
+	for key, value := range template.Headers {
+		headers[render(key)] = render(value)
+	}
+	if template.Headers != nil {
+		template.Headers = headers
+	}
+	return template, err
 }
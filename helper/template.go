@@ -0,0 +1,267 @@
+package helper
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TemplateContext holds what {{...}} placeholders in scripts, env, projects, pipes and webget templates can refer to.
+//
+//	{{name}}             a var from "vars", the value can contain placeholders of its own
+//	{{name|default}}     the default is used if name isn't defined
+//	{{env.HOME}}         an environment variable
+//	{{project.api}}      the path of a project
+//	{{now}}              the current time, {{now:date}}, {{now+1d:unix}} or {{now:2006-01-02}} for other formats
+//	{{sh:git describe}}  the output of a command
+//	\{{                  a literal {{
+type TemplateContext struct {
+	Vars     map[string]string
+	Projects map[string]string
+	// Lenient leaves placeholders that can't be resolved as they are and doesn't run any commands. It's used
+	// when the whole config is rendered up front, the strict rendering is done when a value is actually used.
+	Lenient bool
+}
+
+type UndefinedVarsError struct {
+	Names []string
+}
+
+func (e *UndefinedVarsError) Error() string {
+	return "undefined template variable(s): " + strings.Join(e.Names, ", ")
+}
+
+var templateNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.\-]*$`)
+var templateNowRegexp = regexp.MustCompile(`^now(([+-])([0-9]+)([smhdwMy]))?(:(.+))?$`)
+
+var templateTimeFormats = map[string]string{
+	"date":     "2006-01-02",
+	"time":     "15:04:05",
+	"datetime": "2006-01-02 15:04:05",
+	"iso":      time.RFC3339,
+	"rfc3339":  time.RFC3339,
+}
+
+// NewTemplateContext returns a strict context with the vars and the projects from the config.
+func NewTemplateContext(vars map[string]string) *TemplateContext {
+	context := &TemplateContext{Vars: vars, Projects: map[string]string{}}
+	if config, err := GetEffectiveConfig(""); err == nil && config.Projects != nil {
+		context.Projects = config.Projects
+	}
+	return context
+}
+
+type templateRenderer struct {
+	context   *TemplateContext
+	undefined map[string]bool
+	stack     []string
+}
+
+// Render replaces every placeholder in the text. In strict mode an *UndefinedVarsError listing every
+// undefined var is returned together with the text where those placeholders are left as they are.
+func (c *TemplateContext) Render(text string) (string, error) {
+	renderer := &templateRenderer{context: c, undefined: map[string]bool{}}
+	result, err := renderer.render(text)
+	if err != nil {
+		return text, err
+	}
+	if len(renderer.undefined) > 0 {
+		names := make([]string, 0, len(renderer.undefined))
+		for name := range renderer.undefined {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return result, &UndefinedVarsError{Names: names}
+	}
+	return result, nil
+}
+
+// findTemplateEnd returns the index of the }} closing the placeholder starting at start, allowing nested placeholders.
+func findTemplateEnd(text string, start int) int {
+	depth := 0
+	for index := start; index < len(text)-1; index++ {
+		if text[index] == '{' && text[index+1] == '{' {
+			depth++
+			index++
+		} else if text[index] == '}' && text[index+1] == '}' {
+			depth--
+			if depth == 0 {
+				return index
+			}
+			index++
+		}
+	}
+	return -1
+}
+
+func (r *templateRenderer) render(text string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	var builder strings.Builder
+	for index := 0; index < len(text); {
+		if text[index] == '\\' && strings.HasPrefix(text[index+1:], "{{") {
+			if r.context.Lenient {
+				builder.WriteString(`\{{`)
+			} else {
+				builder.WriteString("{{")
+			}
+			index += 3
+			continue
+		}
+		if !strings.HasPrefix(text[index:], "{{") {
+			builder.WriteByte(text[index])
+			index++
+			continue
+		}
+		end := findTemplateEnd(text, index)
+		if end < 0 {
+			builder.WriteString(text[index:])
+			break
+		}
+		placeholder := text[index : end+2]
+		value, err := r.resolve(placeholder, text[index+2:end])
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(value)
+		index = end + 2
+	}
+	return builder.String(), nil
+}
+
+func (r *templateRenderer) resolve(placeholder string, expression string) (string, error) {
+	expression = strings.TrimSpace(expression)
+	if strings.HasPrefix(expression, "sh:") {
+		if r.context.Lenient {
+			return placeholder, nil
+		}
+		command, err := r.render(strings.TrimSpace(expression[3:]))
+		if err != nil {
+			return "", err
+		}
+		return runTemplateCommand(command)
+	}
+
+	name, defaultValue, hasDefault := cutTemplateDefault(expression)
+	name = strings.TrimSpace(name)
+	if match := templateNowRegexp.FindStringSubmatch(name); match != nil {
+		return formatTemplateTime(match), nil
+	}
+	// Things like {{.State}} in a docker command aren't placeholders for nrun
+	if !templateNameRegexp.MatchString(name) {
+		return placeholder, nil
+	}
+
+	value, found, err := r.lookup(name)
+	if err != nil {
+		return "", err
+	}
+	if found {
+		return value, nil
+	}
+	if hasDefault {
+		return r.render(defaultValue)
+	}
+	if !r.context.Lenient {
+		r.undefined[name] = true
+	}
+	return placeholder, nil
+}
+
+// cutTemplateDefault splits name|default, ignoring any | inside nested placeholders.
+func cutTemplateDefault(expression string) (string, string, bool) {
+	depth := 0
+	for index := 0; index < len(expression); index++ {
+		if strings.HasPrefix(expression[index:], "{{") {
+			depth++
+			index++
+		} else if strings.HasPrefix(expression[index:], "}}") {
+			depth--
+			index++
+		} else if expression[index] == '|' && depth == 0 {
+			return expression[:index], expression[index+1:], true
+		}
+	}
+	return expression, "", false
+}
+
+func (r *templateRenderer) lookup(name string) (string, bool, error) {
+	if strings.HasPrefix(name, "env.") {
+		value, found := os.LookupEnv(name[4:])
+		return value, found, nil
+	}
+	if strings.HasPrefix(name, "project.") {
+		value, found := r.context.Projects[name[8:]]
+		return value, found, nil
+	}
+	value, found := r.context.Vars[name]
+	if !found {
+		return "", false, nil
+	}
+	for index, parent := range r.stack {
+		if parent == name {
+			return "", false, errors.New("template variable cycle: " + strings.Join(append(r.stack[index:], name), " -> "))
+		}
+	}
+	r.stack = append(r.stack, name)
+	defer func() { r.stack = r.stack[:len(r.stack)-1] }()
+	value, err := r.render(value)
+	return value, true, err
+}
+
+func formatTemplateTime(match []string) string {
+	now := time.Now()
+	if len(match[1]) > 0 {
+		amount, _ := strconv.Atoi(match[3])
+		if match[2] == "-" {
+			amount = -amount
+		}
+		switch match[4] {
+		case "s":
+			now = now.Add(time.Duration(amount) * time.Second)
+		case "m":
+			now = now.Add(time.Duration(amount) * time.Minute)
+		case "h":
+			now = now.Add(time.Duration(amount) * time.Hour)
+		case "d":
+			now = now.AddDate(0, 0, amount)
+		case "w":
+			now = now.AddDate(0, 0, amount*7)
+		case "M":
+			now = now.AddDate(0, amount, 0)
+		case "y":
+			now = now.AddDate(amount, 0, 0)
+		}
+	}
+	format := match[6]
+	if format == "unix" {
+		return strconv.FormatInt(now.Unix(), 10)
+	}
+	if layout, ok := templateTimeFormats[format]; ok {
+		return now.Format(layout)
+	}
+	if len(format) == 0 {
+		return now.Format(time.RFC3339)
+	}
+	return now.Format(format)
+}
+
+func runTemplateCommand(command string) (string, error) {
+	shell, err := GetShell()
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.Command(shell, "-c", command)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", errors.New("the command \"" + command + "\" in {{sh:...}} failed: " + err.Error())
+	}
+	return strings.TrimRight(string(output), "\r\n"), nil
+}
@@ -0,0 +1,69 @@
+package helper
+
+import (
+	"os"
+	"testing"
+)
+
+func TestTemplateRender(t *testing.T) {
+	os.Setenv("NRUN_TEMPLATE_TEST", "from-env")
+	context := &TemplateContext{
+		Vars: map[string]string{
+			"host":  `local"host\`,
+			"url":   "http://{{host}}:{{port|3000}}",
+			"empty": "",
+		},
+		Projects: map[string]string{"api": "/srv/api"},
+	}
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"{{host}}", `local"host\`},
+		{"{{ url }}", `http://local"host\:3000`},
+		{"{{port|{{host}}}}", `local"host\`},
+		{"{{empty|default}}", ""},
+		{"{{env.NRUN_TEMPLATE_TEST}}", "from-env"},
+		{"{{project.api}}/src", "/srv/api/src"},
+		{"{{sh:echo hello}}", "hello"},
+		{"docker inspect -f '{{.State.Status}}'", "docker inspect -f '{{.State.Status}}'"},
+		{`\{{host}}`, "{{host}}"},
+		{"{{now:2006}}", ""},
+	}
+	for _, test := range tests {
+		result, err := context.Render(test.text)
+		if err != nil {
+			t.Errorf("Render(%q) returned error %v", test.text, err)
+			continue
+		}
+		if test.text == "{{now:2006}}" {
+			if len(result) != 4 {
+				t.Errorf("Render(%q) = %q, expected a year", test.text, result)
+			}
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("Render(%q) = %q, expected %q", test.text, result, test.expected)
+		}
+	}
+}
+
+func TestTemplateRenderErrors(t *testing.T) {
+	context := &TemplateContext{Vars: map[string]string{"a": "{{b}}", "b": "{{a}}"}}
+	if _, err := context.Render("{{a}}"); err == nil {
+		t.Errorf("a cycle between vars should return an error")
+	}
+	result, err := context.Render("{{missing}} {{other}} {{missing}}")
+	undefined, ok := err.(*UndefinedVarsError)
+	if !ok || len(undefined.Names) != 2 || undefined.Names[0] != "missing" || undefined.Names[1] != "other" {
+		t.Errorf("expected missing and other to be undefined, got %v", err)
+	}
+	if result != "{{missing}} {{other}} {{missing}}" {
+		t.Errorf("undefined placeholders should be left as they are, got %q", result)
+	}
+
+	context.Lenient = true
+	if result, err := context.Render("{{missing}} {{sh:exit 1}}"); err != nil || result != "{{missing}} {{sh:exit 1}}" {
+		t.Errorf("lenient rendering should keep undefined vars and commands, got %q, %v", result, err)
+	}
+}
@@ -27,9 +27,7 @@ func WebGet(args []string, flagList *FlagList) {
 	body := ""
 	headers := make(map[string]string)
 	if flagList.XAuthToken != nil && len(*flagList.XAuthToken) > 0 {
-		usr, _ := user.Current()
-		dir := usr.HomeDir
-		config, err := ReadConfig(dir + "/.nrun.json")
+		config, err := GetEffectiveConfig("")
 
 		if err != nil {
 			fmt.Println("Failed to read config:", err)
@@ -70,7 +68,7 @@ func WebGetTemplate(args []string, flagList *FlagList) {
 
 	requestStart := time.Now()
 
-	config, err := ReadConfig(dir + "/.nrun.json")
+	config, err := GetEffectiveConfig("")
 
 	if err != nil {
 		fmt.Println("Failed to read config:", err)
@@ -89,7 +87,7 @@ func WebGetTemplate(args []string, flagList *FlagList) {
 		return
 	}
 
-	template = ApplyVarsTemplateStruct(template, flagList.Vars)
+	template, err = RenderWebGetTemplate(template, flagList.Vars)
 
 	if err != nil {
 		fmt.Println("Error:", err)
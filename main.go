@@ -7,7 +7,6 @@ import (
 	"log"
 	"nrun/helper"
 	"os"
-	"os/user"
 	"time"
 )
 
@@ -38,6 +37,50 @@ func process() (int, error) {
 	// Parse command line flags
 	args := flag.Args()
 
+	if flagList.ConfigCheck != nil && *flagList.ConfigCheck {
+		return helper.ConfigCheck(helper.GetConfigFiles(originalPath)), nil
+	}
+	// The shell integration doesn't depend on the config, so a broken config doesn't break the shell
+	if flagList.ShellInit != nil && *flagList.ShellInit != "" {
+		if err := helper.ShellInit(*flagList.ShellInit, flagList); err != nil {
+			return 1, err
+		}
+		return 0, nil
+	}
+	if flagList.ConfigRestore != nil && *flagList.ConfigRestore {
+		if err := helper.RestoreConfig(args, flagList); err != nil {
+			return 1, err
+		}
+		return 0, nil
+	}
+	if flagList.ConfigMigrate != nil && *flagList.ConfigMigrate {
+		if err := helper.ConfigMigrate(flagList); err != nil {
+			return 1, err
+		}
+		return 0, nil
+	}
+	if err := helper.VerifyConfigFiles(helper.GetConfigFiles(originalPath)...); err != nil {
+		return 1, err
+	}
+	if _, err := helper.GetEffectiveConfigDocument(originalPath); err != nil {
+		return 1, errors.New("Invalid config: " + err.Error())
+	}
+	if err := helper.SelectProfile(flagList); err != nil {
+		return 1, err
+	}
+	if flagList.ShellHook != nil && *flagList.ShellHook != "" {
+		if err := helper.ShellHook(*flagList.ShellHook, flagList); err != nil {
+			return 1, err
+		}
+		return 0, nil
+	}
+	if flagList.ConfigCommand != nil && *flagList.ConfigCommand {
+		if err := helper.ConfigCommand(args, flagList); err != nil {
+			return 1, err
+		}
+		return 0, nil
+	}
+
 	if flagList.UnpackJWTToken != nil && *flagList.UnpackJWTToken != false {
 		if len(args) == 0 {
 			return 0, helper.UnpackJWTToken("")
@@ -124,7 +167,7 @@ func process() (int, error) {
 	}
 
 	if *flagList.ListProjects == true {
-		helper.ListProjectsFromConfig()
+		helper.ListProjectsFromConfig(flagList)
 		return 0, nil
 	}
 
@@ -133,6 +176,20 @@ func process() (int, error) {
 		return 0, nil
 	}
 
+	if flagList.Discover != nil && *flagList.Discover != "" {
+		if err := helper.DiscoverProjects(*flagList.Discover, flagList); err != nil {
+			return 1, err
+		}
+		return 0, nil
+	}
+
+	if flagList.ProjectCommand != nil && *flagList.ProjectCommand {
+		if err := helper.ProjectCommand(flag.Args(), flagList); err != nil {
+			return 1, err
+		}
+		return 0, nil
+	}
+
 	if *flagList.RemoveProject == true {
 		helper.RemoveProjectFromConfig(flag.Args())
 		return 0, nil
@@ -149,7 +206,7 @@ func process() (int, error) {
 	}
 
 	if *flagList.VersionInformatrion == true {
-		helper.VersionInformation()
+		helper.VersionInformation(flag.Args())
 		return 0, nil
 	}
 	if *flagList.UseAnotherPath == "" {
@@ -163,6 +220,10 @@ func process() (int, error) {
 		_, _, projects, _, _, _, _ := helper.GetDefaultValues("")
 		path = *flagList.UseAnotherPath
 		if _, ok := projects[path]; ok {
+			// nrun -p <project> (or NRUNPROJECT) without a script runs the default script of the project, if it has one
+			if details, ok := helper.GetProjectDetails(path); ok && len(script) == 0 && !*flagList.ShowList {
+				script = details.Script
+			}
 			path = projects[path]
 		}
 		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
@@ -178,6 +239,13 @@ func process() (int, error) {
 		}
 	}
 
+	if flagList.ConfigShow != nil && *flagList.ConfigShow {
+		if err := helper.ShowEffectiveConfig(path, flagList); err != nil {
+			return 1, err
+		}
+		return 0, nil
+	}
+
 	defaultValues, defaultEnvironment, projects, scripts, vars, packageJSONOverrides, pipes := helper.GetDefaultValues(path)
 
 	// Check if we should skip overriding stuff
@@ -233,16 +301,17 @@ func process() (int, error) {
 
 	if flagList.ExecuteScript != nil && *flagList.ExecuteScript == true {
 		if len(scripts) > 0 && len(scripts[script]) > 0 {
-			helper.ExecuteScripts(path, script, scripts[script], args, flagList)
-		} else {
-			log.Println("No script found")
+			if err := helper.RunRequirementCheck(*packageJSON, path, flagList); err != nil {
+				return 1, err
+			}
+			return helper.ExecuteScripts(path, script, scripts, args, flagList), nil
 		}
+		log.Println("No script found")
 		return 0, nil
 	}
 
 	if flagList.ExecuteScriptInProjects != nil && *flagList.ExecuteScriptInProjects == true {
-		helper.ExecuteScriptList(script, scripts, args, projects, flagList)
-		return 0, nil
+		return helper.ExecuteScriptList(script, scripts, args, projects, flagList), nil
 	}
 
 	if flagList.ShowExecutableScript != nil && *flagList.ShowExecutableScript != "" {
@@ -290,6 +359,10 @@ func process() (int, error) {
 		return 0, nil
 	}
 
+	if flagList.DependencyCheck != nil && *flagList.DependencyCheck {
+		return helper.ShowDependencyConsistency(*packageJSON, path, flagList), nil
+	}
+
 	if flagList.WebGetTemplate != nil && len(*flagList.WebGetTemplate) > 0 {
 		if len(script) > 0 {
 			args = append([]string{script}, args...)
@@ -307,9 +380,10 @@ func process() (int, error) {
 	}
 
 	if flagList.ExecuteAlias != nil && *flagList.ExecuteAlias {
-		usr, _ := user.Current()
-		dir := usr.HomeDir
-		config, _ := helper.ReadConfig(dir + "/.nrun.json")
+		config, err := helper.GetEffectiveConfig("")
+		if err != nil {
+			return 1, err
+		}
 		os.Chdir(flagList.UsedPath)
 		for _, alias := range flag.Args() {
 			command := config.Alias[alias]
@@ -321,7 +395,7 @@ func process() (int, error) {
 	}
 
 	//if processErr != nil {
-	//	if helper.PassthruNpm(*packageJSON, script, args, defaultEnvironment, Version) == false {
+	//	if helper.PassthruNpm(*packageJSON, path, script, args, defaultEnvironment, Version) == false {
 	//		log.Println(processErr)
 	//	}
 	//	return
@@ -329,9 +403,16 @@ func process() (int, error) {
 	if len(script) == 0 || *flagList.ShowList == true {
 		helper.ShowScripts(*packageJSON, defaultValues, defaultEnvironment)
 	} else if *flagList.ShowScript == true {
-		helper.ShowScript(*packageJSON, script)
+		helper.ShowScript(*packageJSON, script, path, flagList)
 	} else {
-		return helper.RunNPM(*packageJSON, path, script, args, defaultEnvironment, flagList, Version, pipes)
+		if err := helper.RunRequirementCheck(*packageJSON, path, flagList); err != nil {
+			return 1, err
+		}
+		exitCode, err := helper.RunNPM(*packageJSON, path, script, args, defaultEnvironment, flagList, Version, pipes)
+		if _, ok := packageJSON.Scripts[script]; ok {
+			helper.RecordRun(path, script, timeStarted, exitCode)
+		}
+		return exitCode, err
 	}
 	//}
 	return 0, nil